@@ -0,0 +1,231 @@
+package arc
+
+import (
+	"container/list"
+	"geecache/policy"
+	"time"
+)
+
+// Cache is an Adaptive Replacement Cache, see
+// "ARC: A Self-Tuning, Low Overhead Replacement Cache" by Megiddo and Modha.
+// t1 holds entries seen once recently, t2 entries seen at least twice,
+// b1 and b2 are ghost lists of keys recently evicted from t1 and t2.
+// Hits on ghosts adapt the target size of t1, so that a scan only
+// flushes t1 and leaves the frequently used entries in t2 alone.
+// Sizes are counted in bytes. It is not safe for concurrent access.
+type Cache struct {
+	maxBytes int64
+	p        int64 // target bytes of t1
+	t1, t2   segment
+	b1, b2   segment
+	cache    map[string]*list.Element // entries of all the four lists
+	// optional and executed when an entry is purged.
+	OnEvicted func(key string, value policy.Value, reason policy.EvictReason)
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+type segment struct {
+	ll     *list.List
+	nbytes int64
+}
+
+type entry struct {
+	key    string
+	value  policy.Value // nil for ghosts
+	expire time.Time
+	size   int64
+	seg    *segment
+}
+
+var _ policy.Policy = (*Cache)(nil)
+
+// New is the Constructor of Cache
+func New(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) *Cache {
+	c := &Cache{
+		maxBytes:  maxBytes,
+		cache:     make(map[string]*list.Element),
+		OnEvicted: onEvicted,
+		now:       time.Now,
+	}
+	for _, s := range []*segment{&c.t1, &c.t2, &c.b1, &c.b2} {
+		s.ll = list.New()
+	}
+	return c
+}
+
+// Add adds a value to the cache, it never expires.
+func (c *Cache) Add(key string, value policy.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire adds a value expiring at expire, the zero time means never.
+func (c *Cache) AddWithExpire(key string, value policy.Value, expire time.Time) {
+	size := int64(len(key)) + int64(value.Len())
+	ele, ok := c.cache[key]
+	if !ok {
+		c.cache[key] = c.push(&c.t1, &entry{key: key, value: value, expire: expire, size: size})
+		c.replace(false)
+		c.trimGhosts()
+		return
+	}
+	kv := ele.Value.(*entry)
+	switch kv.seg {
+	case &c.t1, &c.t2:
+		kv.seg.nbytes += size - kv.size
+		kv.value, kv.expire, kv.size = value, expire, size
+		c.move(ele, &c.t2)
+		c.replace(false)
+	case &c.b1:
+		// t1 was too small
+		c.p += size * ratio(c.b2.nbytes, c.b1.nbytes)
+		if c.maxBytes != 0 && c.p > c.maxBytes {
+			c.p = c.maxBytes
+		}
+		c.hitGhost(ele, value, expire, size)
+		c.replace(false)
+	case &c.b2:
+		// t2 was too small
+		c.p -= size * ratio(c.b1.nbytes, c.b2.nbytes)
+		if c.p < 0 {
+			c.p = 0
+		}
+		c.hitGhost(ele, value, expire, size)
+		c.replace(true)
+	}
+	c.trimGhosts()
+}
+
+// hitGhost brings a ghost back into t2 with the new value,
+// the ghost leaves its list with its old size
+func (c *Cache) hitGhost(ele *list.Element, value policy.Value, expire time.Time, size int64) {
+	kv := ele.Value.(*entry)
+	c.move(ele, &c.t2)
+	c.t2.nbytes += size - kv.size
+	kv.value, kv.expire, kv.size = value, expire, size
+}
+
+func ratio(a, b int64) int64 {
+	if b == 0 || a <= b {
+		return 1
+	}
+	return a / b
+}
+
+func (c *Cache) push(s *segment, kv *entry) *list.Element {
+	kv.seg = s
+	s.nbytes += kv.size
+	return s.ll.PushFront(kv)
+}
+
+func (c *Cache) move(ele *list.Element, to *segment) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.nbytes -= kv.size
+	c.cache[kv.key] = c.push(to, kv)
+}
+
+// replace evicts entries of t1 or t2 into the ghost lists until the cache fits
+func (c *Cache) replace(hitB2 bool) {
+	for c.maxBytes != 0 && c.t1.nbytes+c.t2.nbytes > c.maxBytes {
+		if c.t1.nbytes > 0 && (c.t1.nbytes > c.p || hitB2 && c.t1.nbytes == c.p || c.t2.nbytes == 0) {
+			c.evict(c.t1.ll.Back(), &c.b1)
+		} else {
+			c.evict(c.t2.ll.Back(), &c.b2)
+		}
+	}
+}
+
+func (c *Cache) evict(ele *list.Element, ghost *segment) {
+	kv := ele.Value.(*entry)
+	value := kv.value
+	c.move(ele, ghost)
+	kv.value = nil
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, value, policy.Capacity)
+	}
+}
+
+// trimGhosts keeps t1+b1 and the whole directory within maxBytes and 2*maxBytes
+func (c *Cache) trimGhosts() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.b1.ll.Len() > 0 && c.t1.nbytes+c.b1.nbytes > c.maxBytes {
+		c.drop(c.b1.ll.Back())
+	}
+	for c.b2.ll.Len() > 0 && c.t1.nbytes+c.t2.nbytes+c.b1.nbytes+c.b2.nbytes > 2*c.maxBytes {
+		c.drop(c.b2.ll.Back())
+	}
+}
+
+// drop forgets an entry of any list
+func (c *Cache) drop(ele *list.Element) *entry {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.nbytes -= kv.size
+	delete(c.cache, kv.key)
+	return kv
+}
+
+// Get look ups a key's value, expired entries are removed lazily
+func (c *Cache) Get(key string) (value policy.Value, ok bool) {
+	value, _, ok = c.GetWithExpire(key)
+	return
+}
+
+// GetWithExpire look ups a key's value and when it expires
+func (c *Cache) GetWithExpire(key string) (value policy.Value, expire time.Time, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	kv := ele.Value.(*entry)
+	if kv.seg != &c.t1 && kv.seg != &c.t2 {
+		return nil, time.Time{}, false
+	}
+	if policy.IsExpired(kv.expire, c.now()) {
+		c.remove(ele, policy.Expired)
+		return nil, time.Time{}, false
+	}
+	c.move(ele, &c.t2)
+	return kv.value, kv.expire, true
+}
+
+// Remove removes the key from the cache
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.remove(ele, policy.Removed)
+	}
+}
+
+func (c *Cache) remove(ele *list.Element, reason policy.EvictReason) {
+	kv := c.drop(ele)
+	if kv.value != nil && c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// RemoveExpired removes all expired items and returns the number of them
+func (c *Cache) RemoveExpired() int {
+	n := 0
+	now := c.now()
+	for _, ele := range c.cache {
+		kv := ele.Value.(*entry)
+		if kv.value != nil && policy.IsExpired(kv.expire, now) {
+			c.remove(ele, policy.Expired)
+			n++
+		}
+	}
+	return n
+}
+
+// Len the number of cache entries, ghosts are not counted
+func (c *Cache) Len() int {
+	return c.t1.ll.Len() + c.t2.ll.Len()
+}
+
+// Bytes the number of bytes taken by the entries, ghosts are not counted
+func (c *Cache) Bytes() int64 {
+	return c.t1.nbytes + c.t2.nbytes
+}
@@ -0,0 +1,125 @@
+package arc
+
+import (
+	"fmt"
+	"geecache/policy"
+	"testing"
+	"time"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	arc := New(int64(0), nil)
+	arc.Add("key1", String("1234"))
+	if v, ok := arc.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := arc.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+}
+
+func TestScanResistance(t *testing.T) {
+	evicted := 0
+	arc := New(int64(40), func(key string, value policy.Value, reason policy.EvictReason) {
+		if reason != policy.Capacity || value == nil {
+			t.Fatalf("%s evicted for %s", key, reason)
+		}
+		evicted++
+	})
+	// k0 and k1 are used frequently, they move to t2
+	for i := 0; i < 2; i++ {
+		arc.Add(fmt.Sprintf("k%d", i), String("vv"))
+		arc.Get(fmt.Sprintf("k%d", i))
+	}
+	// a scan only flushes t1
+	for i := 0; i < 100; i++ {
+		arc.Add(fmt.Sprintf("s%02d", i), String("vv"))
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := arc.Get(fmt.Sprintf("k%d", i)); !ok {
+			t.Fatalf("k%d should survive the scan", i)
+		}
+	}
+	if arc.Bytes() > 40 || evicted == 0 {
+		t.Fatalf("bytes = %d, evicted = %d", arc.Bytes(), evicted)
+	}
+	// a ghost hit grows the target of t1
+	if ele := arc.b1.ll.Front(); ele != nil {
+		key := ele.Value.(*entry).key
+		if _, ok := arc.Get(key); ok {
+			t.Fatalf("ghost %s should miss", key)
+		}
+		arc.Add(key, String("vv"))
+		if arc.p == 0 {
+			t.Fatalf("p should grow after a hit of b1")
+		}
+	} else {
+		t.Fatal("b1 should not be empty")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+	arc := New(int64(0), nil)
+	arc.now = func() time.Time { return now }
+	arc.AddWithExpire("key1", String("1"), now.Add(time.Second))
+	arc.AddWithExpire("key2", String("2"), now.Add(time.Second))
+	arc.Add("key3", String("3"))
+	now = now.Add(time.Second)
+	if _, ok := arc.Get("key1"); ok {
+		t.Fatalf("key1 should be expired")
+	}
+	if n := arc.RemoveExpired(); n != 1 || arc.Len() != 1 {
+		t.Fatalf("RemoveExpired removed %d items", n)
+	}
+}
+
+// checkBytes verifies the bytes of every list against the sizes of its entries
+func checkBytes(t *testing.T, c *Cache) {
+	t.Helper()
+	for name, s := range map[string]*segment{"t1": &c.t1, "t2": &c.t2, "b1": &c.b1, "b2": &c.b2} {
+		var n int64
+		for ele := s.ll.Front(); ele != nil; ele = ele.Next() {
+			n += ele.Value.(*entry).size
+		}
+		if s.nbytes != n {
+			t.Fatalf("%s.nbytes = %d, want %d", name, s.nbytes, n)
+		}
+	}
+}
+
+func TestGhostHitWithNewSize(t *testing.T) {
+	arc := New(int64(40), nil)
+	arc.Add("k1", String("12345678"))
+	arc.Get("k1")
+	for i := 2; i <= 5; i++ {
+		arc.Add(fmt.Sprintf("k%d", i), String("12345678"))
+	}
+	if ele := arc.cache["k2"]; ele == nil || ele.Value.(*entry).seg != &arc.b1 {
+		t.Fatalf("k2 should be a ghost of b1")
+	}
+	// a bigger value than the ghost
+	arc.Add("k2", String("1234567890123456"))
+	checkBytes(t, arc)
+	if v, ok := arc.Get("k2"); !ok || string(v.(String)) != "1234567890123456" {
+		t.Fatalf("k2 = %v", v)
+	}
+
+	arc.Get("k5")
+	arc.Add("k6", String("12345678"))
+	if ele := arc.cache["k1"]; ele == nil || ele.Value.(*entry).seg != &arc.b2 {
+		t.Fatalf("k1 should be a ghost of b2")
+	}
+	// a smaller value than the ghost
+	arc.Add("k1", String("1"))
+	checkBytes(t, arc)
+	if v, ok := arc.Get("k1"); !ok || string(v.(String)) != "1" || arc.Bytes() > 40 {
+		t.Fatalf("k1 = %v, bytes = %d", v, arc.Bytes())
+	}
+}
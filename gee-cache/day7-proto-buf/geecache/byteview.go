@@ -1,8 +1,16 @@
 package geecache
 
+import "time"
+
 // A ByteView holds an immutable view of bytes.
 type ByteView struct {
 	b []byte
+	e time.Time // expire time, zero means never expires
+}
+
+// Expire returns when the view expires, the zero time means never
+func (v ByteView) Expire() time.Time {
+	return v.e
 }
 
 // Len returns the view's length
@@ -1,35 +1,69 @@
 package geecache
 
 import (
-	"geecache/lru"
+	"geecache/policy"
 	"sync"
 )
 
 type cache struct {
 	mu         sync.Mutex
-	lru        *lru.Cache
+	lru        policy.Policy
+	newPolicy  NewPolicy // LRU if nil
 	cacheBytes int64
+	nhit, nget int64
+	nevict     int64 // number of evictions for capacity
 }
 
 func (c *cache) add(key string, value ByteView) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.lru == nil {
-		c.lru = lru.New(c.cacheBytes, nil)
+		newPolicy := c.newPolicy
+		if newPolicy == nil {
+			newPolicy = LRU
+		}
+		c.lru = newPolicy(c.cacheBytes, func(key string, value policy.Value, reason policy.EvictReason) {
+			if reason == policy.Capacity {
+				c.nevict++
+			}
+		})
 	}
-	c.lru.Add(key, value)
+	c.lru.AddWithExpire(key, value, value.e)
 }
 
 func (c *cache) get(key string) (value ByteView, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.nget++
 	if c.lru == nil {
 		return
 	}
 
-	if v, ok := c.lru.Get(key); ok {
+	if v, _, ok := c.lru.GetWithExpire(key); ok {
+		c.nhit++
 		return v.(ByteView), ok
 	}
 
 	return
 }
+
+// removeExpired is called by the janitor
+func (c *cache) removeExpired() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return 0
+	}
+	return c.lru.RemoveExpired()
+}
+
+func (c *cache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CacheStats{Gets: c.nget, Hits: c.nhit, Evictions: c.nevict}
+	if c.lru != nil {
+		stats.Bytes = c.lru.Bytes()
+		stats.Items = int64(c.lru.Len())
+	}
+	return stats
+}
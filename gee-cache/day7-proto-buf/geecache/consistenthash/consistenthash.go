@@ -30,11 +30,16 @@ func New(replicas int, fn Hash) *Map {
 	return m
 }
 
-// Add adds some keys to the hash.
+// Add adds some keys to the hash, replicas never share a hash.
 func (m *Map) Add(keys ...string) {
 	for _, key := range keys {
 		for i := 0; i < m.replicas; i++ {
 			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			if _, ok := m.hashMap[hash]; ok {
+				// the key is added again, or a replica of another key
+				// has the same hash, which keeps it
+				continue
+			}
 			m.keys = append(m.keys, hash)
 			m.hashMap[hash] = key
 		}
@@ -42,6 +47,33 @@ func (m *Map) Add(keys ...string) {
 	sort.Ints(m.keys)
 }
 
+// Remove removes some keys from the hash,
+// only the items of the removed keys move to their next replicas.
+func (m *Map) Remove(keys ...string) {
+	removed := make(map[int]bool)
+	for _, key := range keys {
+		for i := 0; i < m.replicas; i++ {
+			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+			// a replica of another key may have the same hash
+			if m.hashMap[hash] == key {
+				delete(m.hashMap, hash)
+				removed[hash] = true
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	// filter in place, the slice stays sorted
+	kept := m.keys[:0]
+	for _, hash := range m.keys {
+		if !removed[hash] {
+			kept = append(kept, hash)
+		}
+	}
+	m.keys = kept
+}
+
 // Get gets the closest item in the hash to the provided key.
 func (m *Map) Get(key string) string {
 	if len(m.keys) == 0 {
@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
 )
@@ -41,3 +42,88 @@ func TestHashing(t *testing.T) {
 	}
 
 }
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	hash.Add("6", "4", "2")
+	// Removes 4, 14, 24
+	hash.Remove("4")
+
+	testCases := map[string]string{
+		"2":  "2",
+		"11": "2",
+		"23": "6",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+	if len(hash.keys) != 6 || len(hash.hashMap) != 6 {
+		t.Fatalf("replicas of 4 should be removed, keys = %v", hash.keys)
+	}
+}
+
+func TestAddCollision(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+	// 2: 2, 12, 22 and 12: 12, 112, 212, the first 12 is kept
+	hash.Add("2", "12", "2")
+	if len(hash.keys) != 5 || len(hash.hashMap) != 5 {
+		t.Fatalf("duplicated hashes, keys = %v", hash.keys)
+	}
+	if hash.Get("12") != "2" {
+		t.Fatalf("Asking for 12, should have yielded 2")
+	}
+	hash.Remove("12")
+	if len(hash.keys) != 3 || len(hash.hashMap) != 3 {
+		t.Fatalf("orphaned hashes, keys = %v", hash.keys)
+	}
+	for _, k := range []string{"12", "100", "300"} {
+		if hash.Get(k) != "2" {
+			t.Errorf("Asking for %s, should have yielded 2", k)
+		}
+	}
+}
+
+func TestRemoveMovesOnlyItsKeys(t *testing.T) {
+	const nodes, keys = 5, 10000
+	hash := New(50, nil)
+	for i := 0; i < nodes; i++ {
+		hash.Add(fmt.Sprintf("node%d", i))
+	}
+	before := make([]string, keys)
+	for i := range before {
+		before[i] = hash.Get(fmt.Sprintf("key%d", i))
+	}
+
+	hash.Remove("node3")
+	moved := 0
+	for i, node := range before {
+		after := hash.Get(fmt.Sprintf("key%d", i))
+		if node != "node3" && after != node {
+			t.Fatalf("key%d moved from %s to %s", i, node, after)
+		}
+		if after != node {
+			moved++
+		}
+	}
+	// about 1/N of the keys move, with the variance of 50 replicas
+	if ratio := float64(moved) / keys; ratio < 0.5/nodes || ratio > 1.5/nodes {
+		t.Fatalf("%.2f%% of keys moved", ratio*100)
+	}
+
+	// adding it back restores the ring
+	hash.Add("node3")
+	for i, node := range before {
+		if after := hash.Get(fmt.Sprintf("key%d", i)); after != node {
+			t.Fatalf("key%d is on %s, want %s", i, after, node)
+		}
+	}
+}
@@ -1,24 +1,48 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
 	pb "geecache/geecachepb"
 	"geecache/singleflight"
 	"log"
+	"math/rand"
 	"sync"
+	"time"
 )
 
 // A Group is a cache namespace and associated data loaded spread over
 type Group struct {
-	name      string
-	getter    Getter
+	name   string
+	getter Getter
+	// mainCache is the cache of keys owned by this peer,
+	// hotCache keeps popular keys owned by other peers,
+	// which would cost a round trip for each Get otherwise
 	mainCache cache
+	hotCache  cache
 	peers     PeerPicker
 	// use singleflight.Group to make sure that
 	// each key is only fetched once
 	loader *singleflight.Group
+	// ttl is the default expiration of loaded values, 0 means never
+	ttl time.Duration
+	// janitorInterval is the interval of the janitor, 0 means no janitor
+	janitorInterval time.Duration
+	janitorStop     chan struct{}
+	closeOnce       sync.Once
+
+	// Stats are statistics on the group.
+	Stats Stats
 }
 
+const (
+	// hotCacheRatio is the share of the hot cache in the bytes of the group, 1/8
+	hotCacheRatio = 8
+)
+
+// 1 of hotCacheSampling values got from peers is kept in the hot cache
+var hotCacheSampling = 10
+
 // A Getter loads data for a key.
 type Getter interface {
 	Get(key string) ([]byte, error)
@@ -32,13 +56,76 @@ func (f GetterFunc) Get(key string) ([]byte, error) {
 	return f(key)
 }
 
+// A GetterWithTTL loads data for a key and how long it is valid,
+// ttl <= 0 means the default ttl of the group.
+type GetterWithTTL interface {
+	GetWithTTL(key string) ([]byte, time.Duration, error)
+}
+
+// A GetterWithTTLFunc implements GetterWithTTL and Getter with a function.
+type GetterWithTTLFunc func(key string) ([]byte, time.Duration, error)
+
+// GetWithTTL implements GetterWithTTL interface function
+func (f GetterWithTTLFunc) GetWithTTL(key string) ([]byte, time.Duration, error) {
+	return f(key)
+}
+
+// Get implements Getter interface function
+func (f GetterWithTTLFunc) Get(key string) ([]byte, error) {
+	bytes, _, err := f(key)
+	return bytes, err
+}
+
+// A ContextGetter loads data for a key, it should give up when ctx is done.
+// Group prefers it to Get when the Getter implements it.
+type ContextGetter interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+}
+
+// A ContextGetterFunc implements ContextGetter and Getter with a function.
+type ContextGetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+// GetContext implements ContextGetter interface function
+func (f ContextGetterFunc) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return f(ctx, key)
+}
+
+// Get implements Getter interface function
+func (f ContextGetterFunc) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
+
+// A ContextGetterWithTTL is the ContextGetter variant of GetterWithTTL
+type ContextGetterWithTTL interface {
+	GetWithTTLContext(ctx context.Context, key string) ([]byte, time.Duration, error)
+}
+
+// An Option configures a Group created by NewGroup
+type Option func(*Group)
+
+// WithTTL sets the default expiration of values loaded by the group
+func WithTTL(ttl time.Duration) Option {
+	return func(g *Group) {
+		g.ttl = ttl
+	}
+}
+
+// WithJanitor starts a goroutine removing expired values every interval,
+// otherwise they are only removed when they are got or evicted.
+// Stop it with Close, or by replacing the group with NewGroup.
+func WithJanitor(interval time.Duration) Option {
+	return func(g *Group) {
+		g.janitorInterval = interval
+	}
+}
+
 var (
 	mu     sync.RWMutex
 	groups = make(map[string]*Group)
 )
 
 // NewGroup create a new instance of Group
-func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...Option) *Group {
 	if getter == nil {
 		panic("nil Getter")
 	}
@@ -47,9 +134,20 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 	g := &Group{
 		name:      name,
 		getter:    getter,
-		mainCache: cache{cacheBytes: cacheBytes},
+		mainCache: cache{cacheBytes: cacheBytes - cacheBytes/hotCacheRatio},
+		hotCache:  cache{cacheBytes: cacheBytes / hotCacheRatio},
 		loader:    &singleflight.Group{},
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.janitorInterval > 0 {
+		g.janitorStop = make(chan struct{})
+		go g.janitor(g.janitorInterval, g.janitorStop)
+	}
+	if old, ok := groups[name]; ok {
+		old.Close()
+	}
 	groups[name] = g
 	return g
 }
@@ -65,16 +163,58 @@ func GetGroup(name string) *Group {
 
 // Get value for a key from cache
 func (g *Group) Get(key string) (ByteView, error) {
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext is like Get, but it returns ctx.Err() when ctx is done before the value is loaded.
+// The load shared with other callers goes on until all of them give up.
+func (g *Group) GetContext(ctx context.Context, key string) (ByteView, error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
 
-	if v, ok := g.mainCache.get(key); ok {
+	g.Stats.Gets.Add(1)
+	if v, ok := g.lookupCache(key); ok {
 		log.Println("[GeeCache] hit")
+		g.Stats.CacheHits.Add(1)
 		return v, nil
 	}
 
-	return g.load(key)
+	return g.load(ctx, key)
+}
+
+func (g *Group) lookupCache(key string) (value ByteView, ok bool) {
+	if value, ok = g.mainCache.get(key); ok {
+		return
+	}
+	if value, ok = g.hotCache.get(key); ok {
+		g.Stats.HotCacheHits.Add(1)
+	}
+	return
+}
+
+// janitor removes expired entries every interval until stop is closed
+func (g *Group) janitor(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.mainCache.removeExpired()
+			g.hotCache.removeExpired()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Close stops the janitor of the group, it is safe to call more than once
+func (g *Group) Close() {
+	g.closeOnce.Do(func() {
+		if g.janitorStop != nil {
+			close(g.janitorStop)
+		}
+	})
 }
 
 // RegisterPeers registers a PeerPicker for choosing remote peer
@@ -85,20 +225,42 @@ func (g *Group) RegisterPeers(peers PeerPicker) {
 	g.peers = peers
 }
 
-func (g *Group) load(key string) (value ByteView, err error) {
+func (g *Group) load(ctx context.Context, key string) (value ByteView, err error) {
 	// each key is only fetched once (either locally or remotely)
 	// regardless of the number of concurrent callers.
-	viewi, err := g.loader.Do(key, func() (interface{}, error) {
+	g.Stats.Loads.Add(1)
+	viewi, err := g.loader.DoContext(ctx, key, func(ctx context.Context) (interface{}, error) {
+		// another caller may have populated the cache
+		// right before this call entered singleflight
+		if value, ok := g.lookupCache(key); ok {
+			g.Stats.CacheHits.Add(1)
+			return value, nil
+		}
+		g.Stats.LoadsDeduped.Add(1)
 		if g.peers != nil {
 			if peer, ok := g.peers.PickPeer(key); ok {
-				if value, err = g.getFromPeer(peer, key); err == nil {
+				value, err := g.getFromPeer(ctx, peer, key)
+				if err == nil {
+					g.Stats.PeerLoads.Add(1)
 					return value, nil
 				}
+				g.Stats.PeerErrors.Add(1)
+				if ctx.Err() != nil {
+					// every caller gave up or the deadline passed,
+					// loading locally a key owned by the peer doesn't help
+					return nil, ctx.Err()
+				}
 				log.Println("[GeeCache] Failed to get from peer", err)
 			}
 		}
 
-		return g.getLocally(key)
+		value, err := g.getLocally(ctx, key)
+		if err != nil {
+			g.Stats.LocalLoadErrs.Add(1)
+			return nil, err
+		}
+		g.Stats.LocalLoads.Add(1)
+		return value, nil
 	})
 
 	if err == nil {
@@ -107,30 +269,59 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	return
 }
 
-func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value)
-}
-
-func (g *Group) getLocally(key string) (ByteView, error) {
-	bytes, err := g.getter.Get(key)
+func (g *Group) getLocally(ctx context.Context, key string) (ByteView, error) {
+	var bytes []byte
+	var err error
+	var d time.Duration
+	switch getter := g.getter.(type) {
+	case ContextGetterWithTTL:
+		bytes, d, err = getter.GetWithTTLContext(ctx, key)
+	case ContextGetter:
+		bytes, err = getter.GetContext(ctx, key)
+	case GetterWithTTL:
+		bytes, d, err = getter.GetWithTTL(key)
+	default:
+		bytes, err = g.getter.Get(key)
+	}
+	ttl := g.ttl
+	if d > 0 {
+		ttl = d
+	}
 	if err != nil {
 		return ByteView{}, err
 
 	}
 	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	if ttl > 0 {
+		value.e = time.Now().Add(ttl)
+	}
+	g.mainCache.add(key, value)
 	return value, nil
 }
 
-func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
+func (g *Group) getFromPeer(ctx context.Context, peer PeerGetter, key string) (ByteView, error) {
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	var err error
+	if ctxPeer, ok := peer.(ContextPeerGetter); ok {
+		err = ctxPeer.GetContext(ctx, req, res)
+	} else {
+		err = peer.Get(req, res)
+	}
 	if err != nil {
 		return ByteView{}, err
 	}
-	return ByteView{b: res.Value}, nil
+	value := ByteView{b: res.Value}
+	if res.Expire != 0 {
+		value.e = time.Unix(0, res.Expire)
+	}
+	// keep a sample of the values of other peers,
+	// popular keys are likely to be sampled
+	if rand.Intn(hotCacheSampling) == 0 {
+		g.hotCache.add(key, value)
+	}
+	return value, nil
 }
@@ -1,10 +1,14 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
+	pb "geecache/geecachepb"
 	"log"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 )
 
 var db = map[string]string{
@@ -65,3 +69,206 @@ func TestGetGroup(t *testing.T) {
 		t.Fatalf("expect nil, but %s got", group.name)
 	}
 }
+
+func TestGetWithTTL(t *testing.T) {
+	loads := 0
+	gee := NewGroup("ttl", 2<<10, GetterWithTTLFunc(
+		func(key string) ([]byte, time.Duration, error) {
+			loads++
+			if key == "default" {
+				return []byte(key), 0, nil
+			}
+			return []byte(key), 20 * time.Millisecond, nil
+		}), WithTTL(time.Hour), WithJanitor(5*time.Millisecond))
+	defer gee.Close()
+
+	view, err := gee.Get("price")
+	if err != nil || view.Expire().IsZero() || time.Until(view.Expire()) > 20*time.Millisecond {
+		t.Fatalf("expire of price = %v", view.Expire())
+	}
+	if view, _ := gee.Get("default"); time.Until(view.Expire()) < 59*time.Minute {
+		t.Fatalf("expire of default = %v", view.Expire())
+	}
+	gee.Get("price")
+	if loads != 2 {
+		t.Fatalf("price should be cached, %d loads", loads)
+	}
+	time.Sleep(40 * time.Millisecond)
+	// the janitor has removed the expired value
+	if _, ok := gee.mainCache.get("price"); ok {
+		t.Fatal("price should be expired")
+	}
+	gee.Get("price")
+	if loads != 3 {
+		t.Fatalf("price should be reloaded, %d loads", loads)
+	}
+}
+
+func TestJanitorReplaced(t *testing.T) {
+	getter := GetterFunc(func(key string) ([]byte, error) { return []byte(key), nil })
+	old := NewGroup("janitor", 2<<10, getter, WithJanitor(time.Millisecond))
+	gee := NewGroup("janitor", 2<<10, getter, WithJanitor(time.Millisecond))
+	defer gee.Close()
+	select {
+	case <-old.janitorStop:
+	default:
+		t.Fatal("the janitor of the replaced group should be stopped")
+	}
+	old.Close() // more than once
+	select {
+	case <-gee.janitorStop:
+		t.Fatal("the janitor of the new group should run")
+	default:
+	}
+}
+
+func TestPeerTTL(t *testing.T) {
+	gee := NewGroup("peer-ttl", 2<<10, GetterWithTTLFunc(
+		func(key string) ([]byte, time.Duration, error) {
+			return []byte(key), time.Minute, nil
+		}))
+	server := httptest.NewServer(NewHTTPPool(""))
+	defer server.Close()
+
+	getter := &httpGetter{baseURL: server.URL + defaultBasePath}
+	res := &pb.Response{}
+	if err := getter.Get(&pb.Request{Group: "peer-ttl", Key: "Tom"}, res); err != nil {
+		t.Fatal(err)
+	}
+	view, err := gee.getFromPeer(context.Background(), getter, "Tom")
+	if err != nil || view.String() != "Tom" {
+		t.Fatalf("getFromPeer = %v, %v", view, err)
+	}
+	if d := time.Until(view.Expire()); d <= 0 || d > time.Minute || view.Expire().UnixNano() != res.Expire {
+		t.Fatalf("expire from peer = %v", view.Expire())
+	}
+}
+
+// fakePeer owns all keys and answers with the key itself
+type fakePeer struct {
+	gets int
+}
+
+func (p *fakePeer) PickPeer(key string) (PeerGetter, bool) { return p, true }
+
+func (p *fakePeer) Get(in *pb.Request, out *pb.Response) error {
+	p.gets++
+	out.Value = []byte(in.Key)
+	return nil
+}
+
+func TestHotCache(t *testing.T) {
+	defer func(n int) { hotCacheSampling = n }(hotCacheSampling)
+	hotCacheSampling = 1
+
+	gee := NewGroup("hot", 8<<10, GetterFunc(
+		func(key string) ([]byte, error) { return nil, fmt.Errorf("%s not owned", key) }))
+	peer := &fakePeer{}
+	gee.RegisterPeers(peer)
+
+	for i := 0; i < 10; i++ {
+		if view, err := gee.Get("Tom"); err != nil || view.String() != "Tom" {
+			t.Fatalf("Get(Tom) = %v, %v", view, err)
+		}
+	}
+	if peer.gets != 1 {
+		t.Fatalf("the hot key should be fetched from the peer once, got %d", peer.gets)
+	}
+	if hits := gee.Stats.HotCacheHits.Get(); hits != 9 || gee.Stats.PeerLoads.Get() != 1 {
+		t.Fatalf("hot cache hits = %d, peer loads = %s", hits, &gee.Stats.PeerLoads)
+	}
+	if stats := gee.CacheStats(HotCache); stats.Items != 1 || stats.Hits != 9 {
+		t.Fatalf("hot cache stats = %+v", stats)
+	}
+	if stats := gee.CacheStats(MainCache); stats.Items != 0 {
+		t.Fatalf("main cache stats = %+v", stats)
+	}
+	if gee.mainCache.cacheBytes+gee.hotCache.cacheBytes != 8<<10 || gee.hotCache.cacheBytes != 1<<10 {
+		t.Fatalf("main = %d, hot = %d", gee.mainCache.cacheBytes, gee.hotCache.cacheBytes)
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	release := make(chan struct{})
+	gee := NewGroup("slow", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			select {
+			case <-release:
+				return []byte(key), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}))
+
+	done := make(chan ByteView)
+	go func() {
+		view, _ := gee.GetContext(context.Background(), "Tom")
+		done <- view
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "Tom"); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext error = %v", err)
+	}
+	// the shared load goes on for the patient caller
+	close(release)
+	if view := <-done; view.String() != "Tom" {
+		t.Fatalf("GetContext = %q", view.String())
+	}
+}
+
+// slowPeer owns all keys and never answers before ctx is done
+type slowPeer struct{}
+
+func (p slowPeer) PickPeer(key string) (PeerGetter, bool) { return p, true }
+
+func (p slowPeer) Get(in *pb.Request, out *pb.Response) error {
+	return p.GetContext(context.Background(), in, out)
+}
+
+func (p slowPeer) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestSlowPeer(t *testing.T) {
+	gee := NewGroup("slow-peer", 2<<10, GetterFunc(
+		func(key string) ([]byte, error) { return []byte(key), nil }))
+	gee.RegisterPeers(slowPeer{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := gee.GetContext(ctx, "Tom"); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext error = %v", err)
+	}
+	// the load of the key owned by the peer is given up, not done locally
+	time.Sleep(10 * time.Millisecond)
+	if n := gee.Stats.LocalLoads.Get(); n != 0 {
+		t.Fatalf("%d local loads of a key owned by the peer", n)
+	}
+}
+
+func TestPeerDeadline(t *testing.T) {
+	deadlines := make(chan time.Duration, 1)
+	NewGroup("deadline", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				return nil, fmt.Errorf("no deadline")
+			}
+			deadlines <- time.Until(deadline)
+			return []byte(key), nil
+		}))
+	server := httptest.NewServer(NewHTTPPool(""))
+	defer server.Close()
+
+	getter := &httpGetter{baseURL: server.URL + defaultBasePath}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := getter.GetContext(ctx, &pb.Request{Group: "deadline", Key: "Tom"}, &pb.Response{}); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-deadlines; d <= 0 || d > time.Second {
+		t.Fatalf("deadline of the peer = %v", d)
+	}
+}
@@ -23,6 +23,8 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type Request struct {
 	Group                string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
 	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Id                   uint64   `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Timeout              int64    `protobuf:"varint,4,opt,name=timeout,proto3" json:"timeout,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -67,8 +69,25 @@ func (m *Request) GetKey() string {
 	return ""
 }
 
+func (m *Request) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Request) GetTimeout() int64 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
 type Response struct {
 	Value                []byte   `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Expire               int64    `protobuf:"varint,2,opt,name=expire,proto3" json:"expire,omitempty"`
+	Id                   uint64   `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -106,6 +125,27 @@ func (m *Response) GetValue() []byte {
 	return nil
 }
 
+func (m *Response) GetExpire() int64 {
+	if m != nil {
+		return m.Expire
+	}
+	return 0
+}
+
+func (m *Response) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Response) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Request)(nil), "geecachepb.Request")
 	proto.RegisterType((*Response)(nil), "geecachepb.Response")
@@ -114,15 +154,18 @@ func init() {
 func init() { proto.RegisterFile("geecachepb.proto", fileDescriptor_889d0a4ad37a0d42) }
 
 var fileDescriptor_889d0a4ad37a0d42 = []byte{
-	// 148 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x48, 0x4f, 0x4d, 0x4d,
-	0x4e, 0x4c, 0xce, 0x48, 0x2d, 0x48, 0xd2, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x42, 0x88,
-	0x28, 0x19, 0x72, 0xb1, 0x07, 0xa5, 0x16, 0x96, 0xa6, 0x16, 0x97, 0x08, 0x89, 0x70, 0xb1, 0xa6,
-	0x17, 0xe5, 0x97, 0x16, 0x48, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x41, 0x38, 0x42, 0x02, 0x5c,
-	0xcc, 0xd9, 0xa9, 0x95, 0x12, 0x4c, 0x60, 0x31, 0x10, 0x53, 0x49, 0x81, 0x8b, 0x23, 0x28, 0xb5,
-	0xb8, 0x20, 0x3f, 0xaf, 0x38, 0x15, 0xa4, 0xa7, 0x2c, 0x31, 0xa7, 0x34, 0x15, 0xac, 0x87, 0x27,
-	0x08, 0xc2, 0x31, 0xb2, 0xe3, 0xe2, 0x72, 0x07, 0x69, 0x76, 0x06, 0x59, 0x22, 0x64, 0xc0, 0xc5,
-	0xec, 0x9e, 0x5a, 0x22, 0x24, 0xac, 0x87, 0xe4, 0x10, 0xa8, 0x9d, 0x52, 0x22, 0xa8, 0x82, 0x10,
-	0x53, 0x93, 0xd8, 0xc0, 0xee, 0x34, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x5c, 0xd5, 0xdd, 0x09,
-	0xbb, 0x00, 0x00, 0x00,
+	// 207 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x90, 0xbb, 0x4b, 0xc5, 0x30,
+	0x14, 0xc6, 0x49, 0x73, 0x1f, 0xde, 0x83, 0xc8, 0xe5, 0x58, 0x24, 0x38, 0x95, 0x4e, 0x9d, 0x8a,
+	0xe8, 0xee, 0xe2, 0xd0, 0xfd, 0xac, 0x82, 0xd0, 0xc7, 0xa1, 0x06, 0x1f, 0x89, 0x69, 0x22, 0xfa,
+	0xdf, 0x4b, 0x92, 0x8a, 0x8a, 0xdb, 0xf9, 0x7d, 0xc3, 0xf7, 0x38, 0x70, 0x9c, 0x99, 0xc7, 0x7e,
+	0x7c, 0x64, 0x3b, 0xb4, 0xd6, 0x19, 0x6f, 0x10, 0x7e, 0x94, 0xfa, 0x1e, 0xf6, 0xc4, 0x6f, 0x81,
+	0x17, 0x8f, 0x25, 0x6c, 0x67, 0x67, 0x82, 0x55, 0xa2, 0x12, 0xcd, 0x81, 0x32, 0xe0, 0x11, 0xe4,
+	0x13, 0x7f, 0xaa, 0x22, 0x69, 0xf1, 0xc4, 0x33, 0x28, 0xf4, 0xa4, 0x64, 0x25, 0x9a, 0x0d, 0x15,
+	0x7a, 0x42, 0x05, 0x7b, 0xaf, 0x5f, 0xd8, 0x04, 0xaf, 0x36, 0x95, 0x68, 0x24, 0x7d, 0x63, 0xfd,
+	0x00, 0x27, 0xc4, 0x8b, 0x35, 0xaf, 0x0b, 0x47, 0xf7, 0xf7, 0xfe, 0x39, 0x70, 0x72, 0x3f, 0xa5,
+	0x0c, 0x78, 0x01, 0x3b, 0xfe, 0xb0, 0xda, 0x71, 0x0a, 0x90, 0xb4, 0xd2, 0xbf, 0x8c, 0x12, 0xb6,
+	0xec, 0x9c, 0x71, 0x29, 0xe1, 0x40, 0x19, 0xae, 0x6f, 0x01, 0xba, 0x58, 0xf2, 0x2e, 0x8e, 0xc1,
+	0x2b, 0x90, 0x1d, 0x7b, 0x3c, 0x6f, 0x7f, 0x0d, 0x5e, 0xb7, 0x5d, 0x96, 0x7f, 0xc5, 0xdc, 0x69,
+	0xd8, 0xa5, 0x7f, 0xdc, 0x7c, 0x0d, 0x00, 0xd5, 0x77, 0xdb, 0x9d, 0x23, 0x01, 0x00, 0x00,
 }
@@ -1,15 +1,15 @@
 package geecache
 
 import (
+	"context"
 	"fmt"
-	"geecache/consistenthash"
 	pb "geecache/geecachepb"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -17,24 +17,31 @@ import (
 const (
 	defaultBasePath = "/_geecache/"
 	defaultReplicas = 50
+	// defaultTimeout bounds requests to peers whose ctx has no deadline
+	defaultTimeout = 5 * time.Second
+	// timeoutHeader passes the time left before the caller's deadline to the peer,
+	// relative durations don't depend on the clocks of the peers
+	timeoutHeader = "X-Geecache-Timeout"
 )
 
 // HTTPPool implements PeerPicker for a pool of HTTP peers.
 type HTTPPool struct {
 	// this peer's base URL, e.g. "https://example.net:8000"
-	self        string
-	basePath    string
-	mu          sync.Mutex // guards peers and httpGetters
-	peers       *consistenthash.Map
-	httpGetters map[string]*httpGetter // keyed by e.g. "http://10.0.0.2:8008"
+	self     string
+	basePath string
+	peerSet  // keyed by e.g. "http://10.0.0.2:8008"
 }
 
 // NewHTTPPool initializes an HTTP pool of peers.
 func NewHTTPPool(self string) *HTTPPool {
-	return &HTTPPool{
+	p := &HTTPPool{
 		self:     self,
 		basePath: defaultBasePath,
 	}
+	p.newGetter = func(peer string) PeerGetter {
+		return &httpGetter{baseURL: peer + p.basePath}
+	}
+	return p
 }
 
 // Log info with server name
@@ -64,14 +71,25 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	view, err := group.Get(key)
+	ctx := r.Context()
+	if timeout, err := time.ParseDuration(r.Header.Get(timeoutHeader)); err == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	group.Stats.ServerRequests.Add(1)
+	view, err := group.GetContext(ctx, key)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Write the value to the response body as a proto message.
-	body, err := proto.Marshal(&pb.Response{Value: view.ByteSlice()})
+	res := &pb.Response{Value: view.ByteSlice()}
+	if !view.Expire().IsZero() {
+		res.Expire = view.Expire().UnixNano()
+	}
+	body, err := proto.Marshal(res)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -81,25 +99,11 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
-// Set updates the pool's list of peers.
-func (p *HTTPPool) Set(peers ...string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.peers = consistenthash.New(defaultReplicas, nil)
-	p.peers.Add(peers...)
-	p.httpGetters = make(map[string]*httpGetter, len(peers))
-	for _, peer := range peers {
-		p.httpGetters[peer] = &httpGetter{baseURL: peer + p.basePath}
-	}
-}
-
 // PickPeer picks a peer according to key
 func (p *HTTPPool) PickPeer(key string) (PeerGetter, bool) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	if peer := p.peers.Get(key); peer != "" && peer != p.self {
+	if peer, getter := p.pick(key); peer != "" && peer != p.self {
 		p.Log("Pick peer %s", peer)
-		return p.httpGetters[peer], true
+		return getter, true
 	}
 	return nil, false
 }
@@ -111,13 +115,31 @@ type httpGetter struct {
 }
 
 func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
+	return h.GetContext(context.Background(), in, out)
+}
+
+// GetContext requests the peer with the deadline of ctx,
+// or defaultTimeout if ctx has no deadline
+func (h *httpGetter) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
 	u := fmt.Sprintf(
 		"%v%v/%v",
 		h.baseURL,
 		url.QueryEscape(in.GetGroup()),
 		url.QueryEscape(in.GetKey()),
 	)
-	res, err := http.Get(u)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	deadline, _ := ctx.Deadline()
+	req.Header.Set(timeoutHeader, time.Until(deadline).String())
+	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -139,4 +161,4 @@ func (h *httpGetter) Get(in *pb.Request, out *pb.Response) error {
 	return nil
 }
 
-var _ PeerGetter = (*httpGetter)(nil)
+var _ ContextPeerGetter = (*httpGetter)(nil)
@@ -0,0 +1,210 @@
+package lfu
+
+import (
+	"container/list"
+	"geecache/policy"
+	"time"
+)
+
+// Cache is a LFU cache, entries with the same frequency are evicted
+// in LRU order. It is not safe for concurrent access.
+type Cache struct {
+	maxBytes int64
+	nbytes   int64
+	cache    map[string]*list.Element
+	freqs    map[int]*list.List // entries of each frequency, the most recent first
+	minFreq  int
+	// optional and executed when an entry is purged.
+	OnEvicted func(key string, value policy.Value, reason policy.EvictReason)
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+type entry struct {
+	key    string
+	value  policy.Value
+	expire time.Time
+	freq   int
+}
+
+var _ policy.Policy = (*Cache)(nil)
+
+// New is the Constructor of Cache
+func New(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		cache:     make(map[string]*list.Element),
+		freqs:     make(map[int]*list.List),
+		OnEvicted: onEvicted,
+		now:       time.Now,
+	}
+}
+
+// Add adds a value to the cache, it never expires.
+func (c *Cache) Add(key string, value policy.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire adds a value expiring at expire, the zero time means never.
+// Updating a value counts as an access.
+func (c *Cache) AddWithExpire(key string, value policy.Value, expire time.Time) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
+		kv.value = value
+		kv.expire = expire
+		c.touch(ele)
+		c.evict(kv)
+		return
+	}
+	kv := &entry{key: key, value: value, expire: expire, freq: 1}
+	size := int64(len(key)) + int64(value.Len())
+	if c.maxBytes != 0 && size > c.maxBytes {
+		// never fits, don't flush the cache for it
+		if c.OnEvicted != nil {
+			c.OnEvicted(key, value, policy.Capacity)
+		}
+		return
+	}
+	// evict before inserting, so that the new entry is not the least frequent one
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes+size && len(c.cache) > 0 {
+		c.removeLeastFrequent()
+	}
+	c.cache[key] = c.list(1).PushFront(kv)
+	c.nbytes += size
+	c.minFreq = 1
+	c.evict(kv)
+}
+
+// evict removes entries until the cache fits, kv is removed last
+func (c *Cache) evict(kv *entry) {
+	for c.maxBytes != 0 && c.maxBytes < c.nbytes {
+		if len(c.cache) == 1 {
+			c.removeElement(c.cache[kv.key], policy.Capacity)
+			return
+		}
+		ele := c.freqs[c.minFreq].Back()
+		if ele.Value.(*entry) == kv {
+			if ele = ele.Prev(); ele == nil {
+				ele = c.nextBack(c.minFreq)
+			}
+		}
+		c.removeElement(ele, policy.Capacity)
+	}
+}
+
+// nextBack returns the least recent entry of the next frequency after freq
+func (c *Cache) nextBack(freq int) *list.Element {
+	next := 0
+	for f := range c.freqs {
+		if f > freq && (next == 0 || f < next) {
+			next = f
+		}
+	}
+	return c.freqs[next].Back()
+}
+
+func (c *Cache) list(freq int) *list.List {
+	l, ok := c.freqs[freq]
+	if !ok {
+		l = list.New()
+		c.freqs[freq] = l
+	}
+	return l
+}
+
+// touch increases the frequency of the entry
+func (c *Cache) touch(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	c.unlink(ele)
+	kv.freq++
+	c.cache[kv.key] = c.list(kv.freq).PushFront(kv)
+	if _, ok := c.freqs[c.minFreq]; !ok {
+		c.minFreq = kv.freq
+	}
+}
+
+// unlink removes the element from its frequency list
+func (c *Cache) unlink(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	l := c.freqs[kv.freq]
+	l.Remove(ele)
+	if l.Len() == 0 {
+		delete(c.freqs, kv.freq)
+	}
+}
+
+// Get look ups a key's value, expired entries are removed lazily
+func (c *Cache) Get(key string) (value policy.Value, ok bool) {
+	value, _, ok = c.GetWithExpire(key)
+	return
+}
+
+// GetWithExpire look ups a key's value and when it expires
+func (c *Cache) GetWithExpire(key string) (value policy.Value, expire time.Time, ok bool) {
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	kv := ele.Value.(*entry)
+	if policy.IsExpired(kv.expire, c.now()) {
+		c.removeElement(ele, policy.Expired)
+		return nil, time.Time{}, false
+	}
+	c.touch(ele)
+	return kv.value, kv.expire, true
+}
+
+// Remove removes the key from the cache
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele, policy.Removed)
+	}
+}
+
+func (c *Cache) removeLeastFrequent() {
+	if l, ok := c.freqs[c.minFreq]; ok {
+		c.removeElement(l.Back(), policy.Capacity)
+	}
+}
+
+// RemoveExpired removes all expired items and returns the number of them
+func (c *Cache) RemoveExpired() int {
+	n := 0
+	now := c.now()
+	for _, ele := range c.cache {
+		if policy.IsExpired(ele.Value.(*entry).expire, now) {
+			c.removeElement(ele, policy.Expired)
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Cache) removeElement(ele *list.Element, reason policy.EvictReason) {
+	kv := ele.Value.(*entry)
+	c.unlink(ele)
+	delete(c.cache, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	if _, ok := c.freqs[c.minFreq]; !ok && len(c.freqs) > 0 {
+		c.minFreq = 0
+		for f := range c.freqs {
+			if c.minFreq == 0 || f < c.minFreq {
+				c.minFreq = f
+			}
+		}
+	}
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// Len the number of cache entries
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+// Bytes the number of bytes taken by the entries
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
@@ -0,0 +1,67 @@
+package lfu
+
+import (
+	"geecache/policy"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	lfu := New(int64(0), nil)
+	lfu.Add("key1", String("1234"))
+	if v, ok := lfu.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := lfu.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+}
+
+func TestEvictLeastFrequent(t *testing.T) {
+	keys := make([]string, 0)
+	lfu := New(int64(8), func(key string, value policy.Value, reason policy.EvictReason) {
+		keys = append(keys, key)
+	})
+	lfu.Add("k1", String("v1"))
+	lfu.Add("k2", String("v2"))
+	lfu.Get("k1")
+	lfu.Get("k1")
+	lfu.Get("k2")
+	lfu.Add("k3", String("v3")) // k2 is less frequent than k1
+	lfu.Add("k4", String("v4")) // k3 is the least frequent
+	if expect := []string{"k2", "k3"}; !reflect.DeepEqual(expect, keys) {
+		t.Fatalf("evicted %v, expect %v", keys, expect)
+	}
+	if _, ok := lfu.Get("k1"); !ok || lfu.Len() != 2 || lfu.Bytes() != 8 {
+		t.Fatalf("k1 should be kept")
+	}
+
+	// a value larger than the cache is not kept
+	lfu.Add("big", String("0123456789"))
+	if _, ok := lfu.Get("big"); ok || lfu.Len() != 2 {
+		t.Fatalf("big value should be evicted")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+	lfu := New(int64(0), nil)
+	lfu.now = func() time.Time { return now }
+	lfu.AddWithExpire("key1", String("1"), now.Add(time.Second))
+	lfu.AddWithExpire("key2", String("2"), now.Add(time.Second))
+	lfu.Add("key3", String("3"))
+	now = now.Add(time.Second)
+	if _, ok := lfu.Get("key1"); ok {
+		t.Fatalf("key1 should be expired")
+	}
+	if n := lfu.RemoveExpired(); n != 1 || lfu.Len() != 1 {
+		t.Fatalf("RemoveExpired removed %d items", n)
+	}
+}
@@ -1,6 +1,10 @@
 package lru
 
-import "container/list"
+import (
+	"container/list"
+	"geecache/policy"
+	"time"
+)
 
 // Cache is a LRU cache. It is not safe for concurrent access.
 type Cache struct {
@@ -9,38 +13,66 @@ type Cache struct {
 	ll       *list.List
 	cache    map[string]*list.Element
 	// optional and executed when an entry is purged.
-	OnEvicted func(key string, value Value)
+	OnEvicted func(key string, value Value, reason EvictReason)
+	// now returns the current time, replaced in tests
+	now func() time.Time
 }
 
+// EvictReason tells OnEvicted why an entry is purged
+type EvictReason = policy.EvictReason
+
+const (
+	Expired  = policy.Expired
+	Capacity = policy.Capacity
+	Removed  = policy.Removed
+)
+
 type entry struct {
-	key   string
-	value Value
+	key    string
+	value  Value
+	expire time.Time // zero means never expires
 }
 
 // Value use Len to count how many bytes it takes
-type Value interface {
-	Len() int
-}
+type Value = policy.Value
+
+var _ policy.Policy = (*Cache)(nil)
 
 // New is the Constructor of Cache
-func New(maxBytes int64, onEvicted func(string, Value)) *Cache {
+func New(maxBytes int64, onEvicted func(string, Value, EvictReason)) *Cache {
 	return &Cache{
 		maxBytes:  maxBytes,
 		ll:        list.New(),
 		cache:     make(map[string]*list.Element),
 		OnEvicted: onEvicted,
+		now:       time.Now,
 	}
 }
 
-// Add adds a value to the cache.
+// Add adds a value to the cache, it never expires.
 func (c *Cache) Add(key string, value Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithTTL adds a value expiring after ttl, ttl <= 0 means never.
+func (c *Cache) AddWithTTL(key string, value Value, ttl time.Duration) {
+	var expire time.Time
+	if ttl > 0 {
+		expire = c.now().Add(ttl)
+	}
+	c.AddWithExpire(key, value, expire)
+}
+
+// AddWithExpire adds a value expiring at expire, the zero time means never.
+func (c *Cache) AddWithExpire(key string, value Value, expire time.Time) {
 	if ele, ok := c.cache[key]; ok {
 		c.ll.MoveToFront(ele)
 		kv := ele.Value.(*entry)
 		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
 		kv.value = value
+		kv.expire = expire
 	} else {
-		ele := c.ll.PushFront(&entry{key, value})
+		ele := c.ll.PushFront(&entry{key, value, expire})
 		c.cache[key] = ele
 		c.nbytes += int64(len(key)) + int64(value.Len())
 	}
@@ -49,27 +81,67 @@ func (c *Cache) Add(key string, value Value) {
 	}
 }
 
-// Get look ups a key's value
+// Get look ups a key's value, expired entries are removed lazily
 func (c *Cache) Get(key string) (value Value, ok bool) {
+	value, _, ok = c.GetWithExpire(key)
+	return
+}
+
+// GetWithExpire look ups a key's value and when it expires
+func (c *Cache) GetWithExpire(key string) (value Value, expire time.Time, ok bool) {
 	if ele, ok := c.cache[key]; ok {
-		c.ll.MoveToFront(ele)
 		kv := ele.Value.(*entry)
-		return kv.value, true
+		if c.expired(kv) {
+			c.removeElement(ele, Expired)
+			return nil, time.Time{}, false
+		}
+		c.ll.MoveToFront(ele)
+		return kv.value, kv.expire, true
 	}
 	return
 }
 
+func (c *Cache) expired(kv *entry) bool {
+	return policy.IsExpired(kv.expire, c.now())
+}
+
+// Remove removes the key from the cache
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.removeElement(ele, Removed)
+	}
+}
+
 // RemoveOldest removes the oldest item
 func (c *Cache) RemoveOldest() {
 	ele := c.ll.Back()
 	if ele != nil {
-		c.ll.Remove(ele)
-		kv := ele.Value.(*entry)
-		delete(c.cache, kv.key)
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key, kv.value)
+		c.removeElement(ele, Capacity)
+	}
+}
+
+// RemoveExpired removes all expired items and returns the number of them,
+// it is called by janitors as Get only removes the expired item it meets
+func (c *Cache) RemoveExpired() int {
+	n := 0
+	for ele := c.ll.Back(); ele != nil; {
+		prev := ele.Prev()
+		if c.expired(ele.Value.(*entry)) {
+			c.removeElement(ele, Expired)
+			n++
 		}
+		ele = prev
+	}
+	return n
+}
+
+func (c *Cache) removeElement(ele *list.Element, reason EvictReason) {
+	c.ll.Remove(ele)
+	kv := ele.Value.(*entry)
+	delete(c.cache, kv.key)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
 	}
 }
 
@@ -77,3 +149,8 @@ func (c *Cache) RemoveOldest() {
 func (c *Cache) Len() int {
 	return c.ll.Len()
 }
+
+// Bytes the number of bytes taken by the entries
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
@@ -3,6 +3,7 @@ package lru
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 type String string
@@ -38,7 +39,10 @@ func TestRemoveoldest(t *testing.T) {
 
 func TestOnEvicted(t *testing.T) {
 	keys := make([]string, 0)
-	callback := func(key string, value Value) {
+	callback := func(key string, value Value, reason EvictReason) {
+		if reason != Capacity {
+			t.Fatalf("%s evicted for %s", key, reason)
+		}
 		keys = append(keys, key)
 	}
 	lru := New(int64(10), callback)
@@ -63,3 +67,36 @@ func TestAdd(t *testing.T) {
 		t.Fatal("expected 6 but got", lru.nbytes)
 	}
 }
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+	reasons := make(map[string]EvictReason)
+	lru := New(int64(0), func(key string, value Value, reason EvictReason) {
+		reasons[key] = reason
+	})
+	lru.now = func() time.Time { return now }
+	lru.AddWithTTL("key1", String("1"), time.Second)
+	lru.AddWithTTL("key2", String("2"), time.Minute)
+	lru.Add("key3", String("3"))
+	lru.AddWithTTL("key4", String("4"), time.Second)
+
+	if _, expire, ok := lru.GetWithExpire("key1"); !ok || !expire.Equal(now.Add(time.Second)) {
+		t.Fatalf("cache hit key1 failed")
+	}
+	now = now.Add(time.Second)
+	// lazy expiry on Get
+	if _, ok := lru.Get("key1"); ok || reasons["key1"] != Expired || lru.Len() != 3 {
+		t.Fatalf("key1 should be expired")
+	}
+	if n := lru.RemoveExpired(); n != 1 || reasons["key4"] != Expired {
+		t.Fatalf("RemoveExpired removed %d items", n)
+	}
+	now = now.Add(time.Hour)
+	if _, ok := lru.Get("key3"); !ok {
+		t.Fatalf("key3 should never expire")
+	}
+	lru.Remove("key3")
+	if reasons["key3"] != Removed || lru.Len() != 1 || lru.nbytes != int64(len("key2")+1) {
+		t.Fatalf("Remove key3 failed")
+	}
+}
@@ -1,6 +1,11 @@
 package geecache
 
-import pb "geecache/geecachepb"
+import (
+	"context"
+	"geecache/consistenthash"
+	pb "geecache/geecachepb"
+	"sync"
+)
 
 // PeerPicker is the interface that must be implemented to locate
 // the peer that owns a specific key.
@@ -12,3 +17,88 @@ type PeerPicker interface {
 type PeerGetter interface {
 	Get(in *pb.Request, out *pb.Response) error
 }
+
+// ContextPeerGetter is a PeerGetter which gives up when ctx is done,
+// and passes the deadline of ctx to the peer.
+type ContextPeerGetter interface {
+	PeerGetter
+	GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// peerSet is the consistent hash ring of a pool and the getters of its peers,
+// changes wait for lookups in progress, which never see a half-updated ring
+type peerSet struct {
+	mu      sync.RWMutex // guards ring and getters
+	ring    *consistenthash.Map
+	getters map[string]PeerGetter
+
+	newGetter func(peer string) PeerGetter
+	// closeGetter is optional and called when a peer leaves the set
+	closeGetter func(getter PeerGetter)
+}
+
+// Set updates the pool's list of peers.
+func (s *peerSet) Set(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, getter := range s.getters {
+		s.close(getter)
+	}
+	s.ring = consistenthash.New(defaultReplicas, nil)
+	s.getters = make(map[string]PeerGetter, len(peers))
+	s.addLocked(peers)
+}
+
+// AddPeers adds peers to the pool, keys and getters of other peers are kept.
+func (s *peerSet) AddPeers(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ring == nil {
+		s.ring = consistenthash.New(defaultReplicas, nil)
+		s.getters = make(map[string]PeerGetter, len(peers))
+	}
+	s.addLocked(peers)
+}
+
+func (s *peerSet) addLocked(peers []string) {
+	for _, peer := range peers {
+		if _, ok := s.getters[peer]; ok {
+			continue
+		}
+		s.ring.Add(peer)
+		s.getters[peer] = s.newGetter(peer)
+	}
+}
+
+// RemovePeers removes peers from the pool,
+// only their keys move to the other peers.
+func (s *peerSet) RemovePeers(peers ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, peer := range peers {
+		getter, ok := s.getters[peer]
+		if !ok {
+			continue
+		}
+		s.ring.Remove(peer)
+		delete(s.getters, peer)
+		s.close(getter)
+	}
+}
+
+// pick returns the peer owning key and its getter, "" if there are no peers
+func (s *peerSet) pick(key string) (string, PeerGetter) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.ring == nil {
+		return "", nil
+	}
+	peer := s.ring.Get(key)
+	return peer, s.getters[peer]
+}
+
+func (s *peerSet) close(getter PeerGetter) {
+	if s.closeGetter != nil {
+		s.closeGetter(getter)
+	}
+}
@@ -0,0 +1,94 @@
+package geecache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestPoolMembership(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	const nodes, keys = 4, 10000
+	var peers []string
+	for i := 0; i < nodes; i++ {
+		peers = append(peers, fmt.Sprintf("http://10.0.0.%d:8001", i))
+	}
+	owners := func(pool *HTTPPool) []string {
+		list := make([]string, keys)
+		for i := range list {
+			getter, _ := pool.PickPeer(fmt.Sprintf("key%d", i))
+			list[i] = getter.(*httpGetter).baseURL
+		}
+		return list
+	}
+	pool := NewHTTPPool("http://10.0.0.100:8001")
+	pool.AddPeers(peers...)
+	before := owners(pool)
+	// the owners once the last peer is removed
+	expected := NewHTTPPool("http://10.0.0.100:8001")
+	expected.AddPeers(peers[:nodes-1]...)
+	after := owners(expected)
+	kept := pool.getters[peers[0]]
+
+	// lookups going on while the membership changes see the owner
+	// either before or after the change
+	stop := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(started)
+		for i := 0; ; i = (i + 1) % keys {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			getter, ok := pool.PickPeer(fmt.Sprintf("key%d", i))
+			if !ok {
+				t.Errorf("key%d has no owner", i)
+				return
+			}
+			if owner := getter.(*httpGetter).baseURL; owner != before[i] && owner != after[i] {
+				t.Errorf("owner of key%d = %s, want %s or %s", i, owner, before[i], after[i])
+				return
+			}
+		}
+	}()
+	<-started
+	pool.RemovePeers(peers[nodes-1])
+	close(stop)
+	wg.Wait()
+
+	moved := 0
+	for i, owner := range owners(pool) {
+		if owner != after[i] {
+			t.Fatalf("owner of key%d = %s, want %s", i, owner, after[i])
+		}
+		if owner != before[i] {
+			moved++
+		}
+	}
+	if ratio := float64(moved) / keys; ratio < 0.5/nodes || ratio > 1.5/nodes {
+		t.Fatalf("%.2f%% of keys moved after removing 1 of %d peers", ratio*100, nodes)
+	}
+	if pool.getters[peers[0]] != kept {
+		t.Fatal("getters of the other peers should be kept")
+	}
+
+	pool.AddPeers(peers[nodes-1], peers[0])
+	if len(pool.getters) != nodes {
+		t.Fatalf("peers = %v", pool.getters)
+	}
+	for i, owner := range owners(pool) {
+		if owner != before[i] {
+			t.Fatalf("key%d should be back to its owner", i)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package geecache
+
+import (
+	"geecache/arc"
+	"geecache/lfu"
+	"geecache/lru"
+	"geecache/policy"
+	"geecache/tinylfu"
+)
+
+// A NewPolicy creates the eviction policy of a cache limited to maxBytes
+type NewPolicy func(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) policy.Policy
+
+// eviction policies of WithPolicy
+var (
+	// LRU evicts the least recently used entries, the default
+	LRU NewPolicy = func(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) policy.Policy {
+		return lru.New(maxBytes, onEvicted)
+	}
+	// LFU evicts the least frequently used entries
+	LFU NewPolicy = func(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) policy.Policy {
+		return lfu.New(maxBytes, onEvicted)
+	}
+	// ARC balances recency and frequency, and resists scans
+	ARC NewPolicy = func(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) policy.Policy {
+		return arc.New(maxBytes, onEvicted)
+	}
+	// TinyLFU is W-TinyLFU, which only admits entries more popular than the ones they replace
+	TinyLFU NewPolicy = func(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) policy.Policy {
+		return tinylfu.New(maxBytes, onEvicted)
+	}
+)
+
+// WithPolicy sets the eviction policy of the group, LRU by default
+func WithPolicy(newPolicy NewPolicy) Option {
+	return func(g *Group) {
+		g.mainCache.newPolicy = newPolicy
+		g.hotCache.newPolicy = newPolicy
+	}
+}
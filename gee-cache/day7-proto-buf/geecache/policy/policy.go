@@ -0,0 +1,55 @@
+package policy
+
+import "time"
+
+// Value use Len to count how many bytes it takes,
+// an entry takes len(key) + value.Len() bytes of the cache
+type Value interface {
+	Len() int
+}
+
+// EvictReason tells OnEvicted why an entry is purged
+type EvictReason int
+
+const (
+	// Expired means the entry is out of date
+	Expired EvictReason = iota
+	// Capacity means the entry is chosen by the policy when the cache is full
+	Capacity
+	// Removed means the entry is removed by Remove
+	Removed
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Capacity:
+		return "capacity"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Policy is a cache limited to a number of bytes, which decides what to evict
+// when it is full. Implementations are not safe for concurrent access.
+type Policy interface {
+	// AddWithExpire adds a value expiring at expire, the zero time means never
+	AddWithExpire(key string, value Value, expire time.Time)
+	// GetWithExpire look ups a key's value, expired entries are removed lazily
+	GetWithExpire(key string) (value Value, expire time.Time, ok bool)
+	// Remove removes the key from the cache
+	Remove(key string)
+	// RemoveExpired removes all expired entries and returns the number of them
+	RemoveExpired() int
+	// Len the number of cache entries
+	Len() int
+	// Bytes the number of bytes taken by the entries
+	Bytes() int64
+}
+
+// IsExpired reports whether an entry expiring at expire is out of date at now
+func IsExpired(expire, now time.Time) bool {
+	return !expire.IsZero() && !now.Before(expire)
+}
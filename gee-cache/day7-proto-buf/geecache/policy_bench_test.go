@@ -0,0 +1,84 @@
+package geecache
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// traceLen is the number of accesses of each synthetic trace
+const traceLen = 1 << 16
+
+// zipfTrace accesses 10000 keys with a zipf distribution
+func zipfTrace() []string {
+	r := rand.New(rand.NewSource(1))
+	z := rand.NewZipf(r, 1.1, 1, 9999)
+	trace := make([]string, traceLen)
+	for i := range trace {
+		trace[i] = fmt.Sprintf("zipf%d", z.Uint64())
+	}
+	return trace
+}
+
+// scanTrace mixes the zipf trace with scans of keys which are used only once,
+// e.g. a batch job walking through the whole table
+func scanTrace() []string {
+	trace := zipfTrace()
+	for i := 0; i < len(trace); i += 2000 {
+		for j := 0; j < 500 && i+j < len(trace); j++ {
+			trace[i+j] = fmt.Sprintf("scan%d-%d", i, j)
+		}
+	}
+	return trace
+}
+
+// loopTrace accesses 2000 keys in a loop, larger than the cache
+func loopTrace() []string {
+	trace := make([]string, traceLen)
+	for i := range trace {
+		trace[i] = fmt.Sprintf("loop%d", i%2000)
+	}
+	return trace
+}
+
+func BenchmarkHitRatio(b *testing.B) {
+	value := ByteView{b: make([]byte, 16)}
+	// about 1000 entries
+	const cacheBytes = 1000 * 24
+	traces := []struct {
+		name  string
+		trace []string
+	}{
+		{"zipf", zipfTrace()},
+		{"scan", scanTrace()},
+		{"loop", loopTrace()},
+	}
+	policies := []struct {
+		name      string
+		newPolicy NewPolicy
+	}{
+		{"lru", LRU},
+		{"lfu", LFU},
+		{"arc", ARC},
+		{"tinylfu", TinyLFU},
+	}
+	for _, tt := range traces {
+		for _, p := range policies {
+			b.Run(tt.name+"/"+p.name, func(b *testing.B) {
+				var hits, total int
+				for n := 0; n < b.N; n += len(tt.trace) {
+					c := p.newPolicy(cacheBytes, nil)
+					for _, key := range tt.trace {
+						if _, _, ok := c.GetWithExpire(key); ok {
+							hits++
+						} else {
+							c.AddWithExpire(key, value, value.e)
+						}
+						total++
+					}
+				}
+				b.ReportMetric(100*float64(hits)/float64(total), "hit%")
+			})
+		}
+	}
+}
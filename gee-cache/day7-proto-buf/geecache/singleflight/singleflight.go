@@ -1,12 +1,41 @@
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
 
 // call is an in-flight or completed Do call
 type call struct {
 	wg  sync.WaitGroup
 	val interface{}
 	err error
+
+	// for DoContext
+	ctx      *callContext
+	waiters  map[*waiter]bool
+	finished chan struct{}
+	panicked *panicError // fn panicked, re-panicked by the callers
+}
+
+// waiter is a caller of DoContext
+type waiter struct {
+	deadline time.Time
+	ok       bool // has a deadline
+}
+
+// panicError is a panic of the shared fn of DoContext with its stack,
+// the fn runs in its own goroutine, so the panic is passed to the callers
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *panicError) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
 }
 
 // Group represents a class of work and forms a namespace in which
@@ -28,15 +57,19 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 	if c, ok := g.m[key]; ok {
 		g.mu.Unlock()
 		c.wg.Wait()
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
 		return c.val, c.err
 	}
-	c := new(call)
+	c := &call{finished: make(chan struct{})}
 	c.wg.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
 
 	c.val, c.err = fn()
 	c.wg.Done()
+	close(c.finished)
 
 	g.mu.Lock()
 	delete(g.m, key)
@@ -44,3 +77,159 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, err
 
 	return c.val, c.err
 }
+
+// DoContext is like Do, but each caller stops waiting when its own ctx is done,
+// and gets ctx.Err(). The shared fn runs in its own goroutine with a context which
+// keeps the values of the first caller's ctx, whose deadline is the latest one
+// of the callers still waiting, and which is only cancelled when all of them
+// have stopped waiting, so one impatient caller doesn't fail the others.
+// A cancelled fn keeps its key until it returns, later callers wait for it
+// and take its result, or start a new call if it failed.
+// A panic of fn is re-panicked in the callers waiting for it.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	c, ok := g.m[key]
+	for ok && (c.ctx == nil || c.ctx.err != nil) {
+		// a Do call of the same key, or a call given up by all its callers, is in flight
+		g.mu.Unlock()
+		select {
+		case <-c.finished:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if c.ctx == nil || c.err == nil && c.panicked == nil {
+			return c.val, c.err
+		}
+		g.mu.Lock()
+		c, ok = g.m[key]
+	}
+	if !ok {
+		c = &call{waiters: make(map[*waiter]bool), finished: make(chan struct{})}
+		c.ctx = &callContext{parent: ctx, g: g, c: c, done: make(chan struct{})}
+		c.wg.Add(1)
+		g.m[key] = c
+		go g.run(key, c, fn)
+	}
+	w := &waiter{}
+	w.deadline, w.ok = ctx.Deadline()
+	c.waiters[w] = true
+	c.ctx.scheduleLocked()
+	g.mu.Unlock()
+
+	select {
+	case <-c.finished:
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+		return c.val, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		delete(c.waiters, w)
+		if len(c.waiters) == 0 {
+			// nobody waits for the result, fn keeps the key until it returns
+			c.ctx.cancelLocked(context.Canceled)
+		} else {
+			c.ctx.scheduleLocked()
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// run runs the shared fn of DoContext
+func (g *Group) run(key string, c *call, fn func(ctx context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.panicked = &panicError{value: r, stack: debug.Stack()}
+		}
+		g.mu.Lock()
+		if g.m[key] == c {
+			delete(g.m, key)
+		}
+		c.ctx.cancelLocked(context.Canceled)
+		g.mu.Unlock()
+		c.wg.Done()
+		close(c.finished)
+	}()
+	c.val, c.err = fn(c.ctx)
+}
+
+// callContext is the context of a shared call of DoContext,
+// its fields except parent are guarded by the mutex of the Group
+type callContext struct {
+	parent context.Context // for values
+	g      *Group
+	c      *call
+	done   chan struct{}
+	err    error
+	timer  *time.Timer // fires at the latest deadline of the waiters
+}
+
+// Deadline is the latest deadline of the waiting callers,
+// there is no deadline if any of them has none
+func (ctx *callContext) Deadline() (deadline time.Time, ok bool) {
+	ctx.g.mu.Lock()
+	defer ctx.g.mu.Unlock()
+	return ctx.deadlineLocked()
+}
+
+func (ctx *callContext) deadlineLocked() (deadline time.Time, ok bool) {
+	for w := range ctx.c.waiters {
+		if !w.ok {
+			return time.Time{}, false
+		}
+		if w.deadline.After(deadline) {
+			deadline = w.deadline
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
+
+// scheduleLocked resets the timer to the deadline after the waiters change
+func (ctx *callContext) scheduleLocked() {
+	if ctx.timer != nil {
+		ctx.timer.Stop()
+		ctx.timer = nil
+	}
+	deadline, ok := ctx.deadlineLocked()
+	if ctx.err != nil || !ok {
+		return
+	}
+	ctx.timer = time.AfterFunc(time.Until(deadline), func() {
+		ctx.g.mu.Lock()
+		defer ctx.g.mu.Unlock()
+		// the deadline may have been extended by a new waiter meanwhile
+		if deadline, ok := ctx.deadlineLocked(); ok && !time.Now().Before(deadline) {
+			ctx.cancelLocked(context.DeadlineExceeded)
+		}
+	})
+}
+
+func (ctx *callContext) Done() <-chan struct{} {
+	return ctx.done
+}
+
+func (ctx *callContext) Err() error {
+	ctx.g.mu.Lock()
+	defer ctx.g.mu.Unlock()
+	return ctx.err
+}
+
+func (ctx *callContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
+
+func (ctx *callContext) cancelLocked(err error) {
+	if ctx.err != nil {
+		return
+	}
+	ctx.err = err
+	close(ctx.done)
+	if ctx.timer != nil {
+		ctx.timer.Stop()
+		ctx.timer = nil
+	}
+}
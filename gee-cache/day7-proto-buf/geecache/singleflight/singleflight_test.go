@@ -1,7 +1,11 @@
 package singleflight
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestDo(t *testing.T) {
@@ -14,3 +18,192 @@ func TestDo(t *testing.T) {
 		t.Errorf("Do v = %v, error = %v", v, err)
 	}
 }
+
+func TestDoContext(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls int32
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		select {
+		case <-release:
+			return "bar", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	impatient, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := g.DoContext(impatient, "key", fn)
+		errs <- err
+	}()
+	<-started
+	vals := make(chan interface{})
+	go func() {
+		v, _ := g.DoContext(context.Background(), "key", fn)
+		vals <- v
+	}()
+
+	for waiters := 0; waiters < 2; time.Sleep(time.Millisecond) {
+		g.mu.Lock()
+		waiters = len(g.m["key"].waiters)
+		g.mu.Unlock()
+	}
+
+	// the impatient caller leaves, the shared call goes on for the other one
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("impatient caller got %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if v := <-vals; v != "bar" || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("DoContext v = %v, calls = %d", v, calls)
+	}
+}
+
+func TestDoContextAbandoned(t *testing.T) {
+	var g Group
+	cancelled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	if err != context.Canceled {
+		t.Fatalf("DoContext error = %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the call should be cancelled when all callers are gone")
+	}
+}
+
+func TestDoContextDeadline(t *testing.T) {
+	var g Group
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	expect, _ := ctx.Deadline()
+	v, _ := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		deadline, _ := ctx.Deadline()
+		return deadline, nil
+	})
+	if v != expect {
+		t.Fatalf("deadline of the call = %v, want %v", v, expect)
+	}
+}
+
+// deadlineContext reports a deadline, but is never done by itself
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (ctx deadlineContext) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+func TestDoContextDeadlineDone(t *testing.T) {
+	var g Group
+	ctx := deadlineContext{context.Background(), time.Now().Add(20 * time.Millisecond)}
+	_, err := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		// only Done is selected, it is closed at the deadline
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return nil, nil
+		}
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("DoContext error = %v", err)
+	}
+}
+
+func TestDoContextPanic(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		<-release
+		panic("boom")
+	}
+	panics := make(chan interface{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer func() { panics <- recover() }()
+			g.DoContext(context.Background(), "key", fn)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for i := 0; i < 2; i++ {
+		p, ok := (<-panics).(*panicError)
+		if !ok || p.value != "boom" || len(p.stack) == 0 {
+			t.Fatalf("recovered %v, want the panic of fn", p)
+		}
+	}
+	// the key is released
+	if v, err := g.DoContext(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+		return "bar", nil
+	}); v != "bar" || err != nil {
+		t.Fatalf("DoContext = %v, %v", v, err)
+	}
+}
+
+func TestDoContextAbandonedKeepsKey(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release // ignores ctx
+		return "bar", nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.DoContext(ctx, "key", fn); err != context.DeadlineExceeded {
+		t.Fatalf("DoContext error = %v", err)
+	}
+
+	// fn is still running, a later caller waits for it instead of loading again
+	vals := make(chan interface{})
+	go func() {
+		v, _ := g.DoContext(context.Background(), "key", fn)
+		vals <- v
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if v := <-vals; v != "bar" || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("DoContext v = %v, calls = %d", v, calls)
+	}
+}
+
+func TestDoContextJoinsDo(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	defer close(release)
+	go g.Do("key", func() (interface{}, error) {
+		<-release
+		return "bar", nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.DoContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("the Do call is in flight")
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("DoContext error = %v", err)
+	}
+}
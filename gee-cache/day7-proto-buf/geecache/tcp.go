@@ -0,0 +1,348 @@
+package geecache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	pb "geecache/geecachepb"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// maxFrameSize guards against corrupted length prefixes
+	maxFrameSize = 64 << 20
+	// maxConnRequests bounds the requests served at once on a connection,
+	// the connection isn't read any further until one of them is answered
+	maxConnRequests = 128
+	// writeTimeout bounds a response write, so that a peer which doesn't read
+	// its responses can't block the others written on the connection
+	writeTimeout = 5 * time.Second
+)
+
+// errConnClosed fails the pending requests of a broken connection
+var errConnClosed = errors.New("geecache: connection closed")
+
+// writeFrame writes a message prefixed with its 4 bytes big-endian length
+func writeFrame(w io.Writer, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	_, err = w.Write(buf)
+	return err
+}
+
+// readFrame reads a message written by writeFrame
+func readFrame(r io.Reader, msg proto.Message) error {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return fmt.Errorf("geecache: frame of %d bytes is too large", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// TCPPool implements PeerPicker for a pool of TCP peers.
+// Peers talk in length-prefixed geecachepb frames over persistent connections,
+// requests are pipelined and their responses may come back in any order.
+type TCPPool struct {
+	// this peer's address, e.g. "10.0.0.1:8001"
+	self    string
+	peerSet // keyed by e.g. "10.0.0.2:8001", connections are closed when peers leave
+}
+
+// NewTCPPool initializes a TCP pool of peers.
+func NewTCPPool(self string) *TCPPool {
+	p := &TCPPool{self: self}
+	p.newGetter = func(peer string) PeerGetter {
+		return &tcpGetter{addr: peer}
+	}
+	p.closeGetter = func(getter PeerGetter) {
+		getter.(*tcpGetter).close()
+	}
+	return p
+}
+
+// Log info with server name
+func (p *TCPPool) Log(format string, v ...interface{}) {
+	log.Printf("[Server %s] %s", p.self, fmt.Sprintf(format, v...))
+}
+
+// PickPeer picks a peer according to key
+func (p *TCPPool) PickPeer(key string) (PeerGetter, bool) {
+	if peer, getter := p.pick(key); peer != "" && peer != p.self {
+		return getter, true
+	}
+	return nil, false
+}
+
+var _ PeerPicker = (*TCPPool)(nil)
+
+// ListenAndServe listens on the TCP address of this peer and serves other peers
+func (p *TCPPool) ListenAndServe() error {
+	l, err := net.Listen("tcp", p.self)
+	if err != nil {
+		return err
+	}
+	return p.Serve(l)
+}
+
+// Serve accepts connections of other peers on l
+func (p *TCPPool) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go p.serveConn(conn)
+	}
+}
+
+// serveConn answers each request in its own goroutine,
+// so that a slow key doesn't block the ones behind it
+func (p *TCPPool) serveConn(conn net.Conn) {
+	p.Log("connection from %s", conn.RemoteAddr())
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wmu sync.Mutex
+	sem := make(chan struct{}, maxConnRequests)
+	r := bufio.NewReader(conn)
+	for {
+		req := &pb.Request{}
+		if err := readFrame(r, req); err != nil {
+			if err != io.EOF {
+				p.Log("read from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			res := p.serveRequest(ctx, req)
+			wmu.Lock()
+			defer wmu.Unlock()
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := writeFrame(conn, res); err != nil {
+				conn.Close()
+			}
+		}()
+	}
+}
+
+func (p *TCPPool) serveRequest(ctx context.Context, req *pb.Request) *pb.Response {
+	res := &pb.Response{Id: req.Id}
+	group := GetGroup(req.Group)
+	if group == nil {
+		res.Error = "no such group: " + req.Group
+		return res
+	}
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout))
+		defer cancel()
+	}
+	group.Stats.ServerRequests.Add(1)
+	view, err := group.GetContext(ctx, req.Key)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Value = view.ByteSlice()
+	if !view.Expire().IsZero() {
+		res.Expire = view.Expire().UnixNano()
+	}
+	return res
+}
+
+// tcpGetter multiplexes requests to a peer over one connection,
+// it is dialed lazily and redialed after it breaks
+type tcpGetter struct {
+	addr string
+
+	mu      sync.Mutex // guards the fields below
+	conn    net.Conn
+	dialing *dialCall // in-flight dial, shared by the requests waiting for it
+	nextID  uint64
+	pending map[uint64]chan *pb.Response
+	closed  bool
+
+	wmu sync.Mutex // serializes writes to conn
+}
+
+// dialCall is a dial of tcpGetter, done is closed when it completes
+type dialCall struct {
+	done chan struct{}
+	err  error
+}
+
+func (h *tcpGetter) Get(in *pb.Request, out *pb.Response) error {
+	return h.GetContext(context.Background(), in, out)
+}
+
+// GetContext sends the request with the time left of ctx,
+// or defaultTimeout if ctx has no deadline
+func (h *tcpGetter) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+	deadline, _ := ctx.Deadline()
+	ch := make(chan *pb.Response, 1)
+
+	conn, err := h.lockConn(ctx)
+	if err != nil {
+		return err
+	}
+	h.nextID++
+	id := h.nextID
+	h.pending[id] = ch
+	h.mu.Unlock()
+
+	req := &pb.Request{Group: in.Group, Key: in.Key, Id: id, Timeout: int64(time.Until(deadline))}
+	h.wmu.Lock()
+	conn.SetWriteDeadline(deadline)
+	err = writeFrame(conn, req)
+	h.wmu.Unlock()
+	if err != nil {
+		h.mu.Lock()
+		h.failLocked(conn)
+		h.mu.Unlock()
+		return err
+	}
+
+	select {
+	case res := <-ch:
+		if res == nil {
+			return errConnClosed
+		}
+		if res.Error != "" {
+			return fmt.Errorf("server returned: %v", res.Error)
+		}
+		out.Value, out.Expire = res.Value, res.Expire
+		return nil
+	case <-ctx.Done():
+		h.mu.Lock()
+		delete(h.pending, id)
+		h.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// lockConn returns the connection with h.mu held, or an error without it.
+// The dial runs without h.mu, requests wait for the in-flight one until ctx is done.
+func (h *tcpGetter) lockConn(ctx context.Context) (net.Conn, error) {
+	h.mu.Lock()
+	for {
+		if h.closed {
+			h.mu.Unlock()
+			return nil, errConnClosed
+		}
+		if h.conn != nil {
+			return h.conn, nil
+		}
+		d := h.dialing
+		if d == nil {
+			d = &dialCall{done: make(chan struct{})}
+			h.dialing = d
+			go h.dial(d)
+		}
+		h.mu.Unlock()
+		select {
+		case <-d.done:
+			if d.err != nil {
+				return nil, d.err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		h.mu.Lock()
+	}
+}
+
+// dial connects to the peer, it isn't bound to the ctx of any request
+// as the connection is shared by all of them
+func (h *tcpGetter) dial(d *dialCall) {
+	conn, err := net.DialTimeout("tcp", h.addr, defaultTimeout)
+	h.mu.Lock()
+	h.dialing = nil
+	switch {
+	case err != nil:
+		d.err = err
+	case h.closed:
+		conn.Close()
+		d.err = errConnClosed
+	default:
+		h.conn = conn
+		h.pending = make(map[uint64]chan *pb.Response)
+		go h.readLoop(conn)
+	}
+	h.mu.Unlock()
+	close(d.done)
+}
+
+// readLoop dispatches responses to the pending requests by their ids
+func (h *tcpGetter) readLoop(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		res := &pb.Response{}
+		if err := readFrame(r, res); err != nil {
+			h.mu.Lock()
+			h.failLocked(conn)
+			h.mu.Unlock()
+			return
+		}
+		h.mu.Lock()
+		ch, ok := h.pending[res.Id]
+		delete(h.pending, res.Id)
+		h.mu.Unlock()
+		if ok {
+			ch <- res
+		}
+	}
+}
+
+// failLocked closes a broken connection and fails its pending requests,
+// the next request dials again, h.mu must be held
+func (h *tcpGetter) failLocked(conn net.Conn) {
+	if h.conn != conn {
+		return
+	}
+	conn.Close()
+	h.conn = nil
+	for id, ch := range h.pending {
+		ch <- nil
+		delete(h.pending, id)
+	}
+}
+
+// close closes the connection when the peer is removed from the pool
+func (h *tcpGetter) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	if h.conn != nil {
+		h.failLocked(h.conn)
+	}
+}
+
+var _ ContextPeerGetter = (*tcpGetter)(nil)
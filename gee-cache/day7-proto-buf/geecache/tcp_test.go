@@ -0,0 +1,168 @@
+package geecache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	pb "geecache/geecachepb"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startTCPPool(t testing.TB) (*TCPPool, net.Listener) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := NewTCPPool(l.Addr().String())
+	go pool.Serve(l)
+	return pool, l
+}
+
+func TestTCPGetter(t *testing.T) {
+	release := make(chan struct{})
+	NewGroup("tcp", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			if key == "slow" {
+				<-release
+			}
+			if key == "unknown" {
+				return nil, fmt.Errorf("%s not exist", key)
+			}
+			return []byte(key), nil
+		}), WithTTL(time.Minute))
+	pool, l := startTCPPool(t)
+	defer l.Close()
+	getter := &tcpGetter{addr: pool.self}
+	defer getter.close()
+
+	// the slow key doesn't block the others pipelined behind it
+	slow := make(chan *pb.Response)
+	go func() {
+		res := &pb.Response{}
+		getter.Get(&pb.Request{Group: "tcp", Key: "slow"}, res)
+		slow <- res
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			res := &pb.Response{}
+			if err := getter.Get(&pb.Request{Group: "tcp", Key: key}, res); err != nil || string(res.Value) != key || res.Expire == 0 {
+				t.Errorf("Get(%s) = %q, %v", key, res.Value, err)
+			}
+		}(fmt.Sprintf("key%d", i))
+	}
+	wg.Wait()
+	close(release)
+	if res := <-slow; string(res.Value) != "slow" {
+		t.Fatalf("Get(slow) = %q", res.Value)
+	}
+
+	if err := getter.Get(&pb.Request{Group: "tcp", Key: "unknown"}, &pb.Response{}); err == nil {
+		t.Fatal("error of the peer should be returned")
+	}
+	if err := getter.Get(&pb.Request{Group: "no-such-group", Key: "Tom"}, &pb.Response{}); err == nil {
+		t.Fatal("unknown group should fail")
+	}
+
+	// a broken connection is redialed
+	getter.mu.Lock()
+	getter.conn.Close()
+	getter.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	res := &pb.Response{}
+	if err := getter.Get(&pb.Request{Group: "tcp", Key: "Tom"}, res); err != nil || string(res.Value) != "Tom" {
+		t.Fatalf("Get after reconnect = %q, %v", res.Value, err)
+	}
+}
+
+func TestTCPGetterTimeout(t *testing.T) {
+	NewGroup("tcp-timeout", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}))
+	pool, l := startTCPPool(t)
+	defer l.Close()
+	getter := &tcpGetter{addr: pool.self}
+	defer getter.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := getter.GetContext(ctx, &pb.Request{Group: "tcp-timeout", Key: "Tom"}, &pb.Response{}); err != context.DeadlineExceeded {
+		t.Fatalf("GetContext error = %v", err)
+	}
+}
+
+func TestTCPGetterDialing(t *testing.T) {
+	// a dial which never completes, e.g. of a dead peer
+	getter := &tcpGetter{addr: "127.0.0.1:0", dialing: &dialCall{done: make(chan struct{})}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- getter.GetContext(ctx, &pb.Request{Group: "tcp", Key: "Tom"}, &pb.Response{})
+		}()
+	}
+	// the lock isn't held while waiting for the dial
+	time.Sleep(5 * time.Millisecond)
+	getter.mu.Lock()
+	getter.mu.Unlock()
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != context.DeadlineExceeded {
+			t.Fatalf("GetContext error = %v", err)
+		}
+	}
+}
+
+func TestTCPServeConnBounded(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	release := make(chan struct{})
+	NewGroup("tcp-bounded", 2<<10, ContextGetterFunc(
+		func(ctx context.Context, key string) ([]byte, error) {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return []byte(key), nil
+		}))
+	pool, l := startTCPPool(t)
+	defer l.Close()
+	conn, err := net.Dial("tcp", pool.self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const n = maxConnRequests + 50
+	go func() {
+		for i := 0; i < n; i++ {
+			writeFrame(conn, &pb.Request{Group: "tcp-bounded", Key: fmt.Sprintf("key%d", i), Id: uint64(i)})
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	if peak != maxConnRequests {
+		t.Errorf("%d requests served at once, want %d", peak, maxConnRequests)
+	}
+	mu.Unlock()
+	close(release)
+	r := bufio.NewReader(conn)
+	for i := 0; i < n; i++ {
+		if err := readFrame(r, &pb.Response{}); err != nil {
+			t.Fatalf("response %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tinylfu
+
+import "hash/fnv"
+
+// sketchDepth is the number of rows of the count-min sketch
+const sketchDepth = 4
+
+// cmSketch is a count-min sketch of 4-bit counters estimating key frequencies.
+// Counters are halved every resetAt increments, so that old popularity fades.
+type cmSketch struct {
+	rows    [sketchDepth][]byte // two 4-bit counters in a byte
+	seeds   [sketchDepth]uint64
+	mask    uint64
+	added   int
+	resetAt int
+}
+
+// newCMSketch creates a sketch of width counters per row, rounded up to a power of 2
+func newCMSketch(width int) *cmSketch {
+	n := 16
+	for n < width {
+		n <<= 1
+	}
+	s := &cmSketch{mask: uint64(n - 1), resetAt: 10 * n}
+	for i := range s.rows {
+		s.rows[i] = make([]byte, n/2)
+		s.seeds[i] = 0x9e3779b97f4a7c15 * uint64(i+1)
+	}
+	return s
+}
+
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// index returns the counter of the key hash in row i
+func (s *cmSketch) index(h uint64, i int) (int, uint) {
+	x := (h ^ s.seeds[i]) * 0xbf58476d1ce4e5b9
+	x ^= x >> 31
+	n := x & s.mask
+	return int(n / 2), uint(n%2) * 4
+}
+
+// increment adds 1 to the counters of the key
+func (s *cmSketch) increment(key string) {
+	h := hash(key)
+	for i := range s.rows {
+		j, shift := s.index(h, i)
+		if v := (s.rows[i][j] >> shift) & 0x0f; v < 15 {
+			s.rows[i][j] += 1 << shift
+		}
+	}
+	s.added++
+	if s.added >= s.resetAt {
+		s.reset()
+	}
+}
+
+// estimate returns the minimum counter of the key
+func (s *cmSketch) estimate(key string) byte {
+	h := hash(key)
+	min := byte(15)
+	for i := range s.rows {
+		j, shift := s.index(h, i)
+		if v := (s.rows[i][j] >> shift) & 0x0f; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// reset halves all counters
+func (s *cmSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] = (s.rows[i][j] >> 1) & 0x77
+		}
+	}
+	s.added /= 2
+}
@@ -0,0 +1,228 @@
+package tinylfu
+
+import (
+	"container/list"
+	"geecache/policy"
+	"time"
+)
+
+// Cache is a W-TinyLFU cache, see "TinyLFU: A Highly Efficient Cache
+// Admission Policy" by Einziger, Friedman and Manes.
+// New entries go to a small LRU window. Entries leaving the window are
+// admitted to the main SLRU only if the count-min sketch estimates them
+// more popular than the entry the main SLRU would evict,
+// so that one-hit wonders of a scan don't flush popular entries.
+// Sizes are counted in bytes. It is not safe for concurrent access.
+type Cache struct {
+	maxBytes  int64
+	window    segment // 1% of maxBytes
+	probation segment // entries of main seen once there
+	protected segment // 80% of main at most
+	cache     map[string]*list.Element
+	sketch    *cmSketch
+	// optional and executed when an entry is purged.
+	OnEvicted func(key string, value policy.Value, reason policy.EvictReason)
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+type segment struct {
+	ll       *list.List
+	nbytes   int64
+	maxBytes int64
+}
+
+type entry struct {
+	key    string
+	value  policy.Value
+	expire time.Time
+	size   int64
+	seg    *segment
+}
+
+var _ policy.Policy = (*Cache)(nil)
+
+// averageEntryBytes estimates the number of entries to size the sketch
+const averageEntryBytes = 64
+
+// New is the Constructor of Cache
+func New(maxBytes int64, onEvicted func(string, policy.Value, policy.EvictReason)) *Cache {
+	window := maxBytes / 100
+	if window == 0 && maxBytes > 0 {
+		window = 1
+	}
+	main := maxBytes - window
+	entries := maxBytes / averageEntryBytes
+	if entries < 1024 || maxBytes == 0 {
+		entries = 1024
+	} else if entries > 1<<20 {
+		entries = 1 << 20
+	}
+	c := &Cache{
+		maxBytes:  maxBytes,
+		window:    segment{ll: list.New(), maxBytes: window},
+		probation: segment{ll: list.New()},
+		protected: segment{ll: list.New(), maxBytes: main * 8 / 10},
+		cache:     make(map[string]*list.Element),
+		sketch:    newCMSketch(int(entries)),
+		OnEvicted: onEvicted,
+		now:       time.Now,
+	}
+	c.probation.maxBytes = main
+	return c
+}
+
+// Add adds a value to the cache, it never expires.
+func (c *Cache) Add(key string, value policy.Value) {
+	c.AddWithExpire(key, value, time.Time{})
+}
+
+// AddWithExpire adds a value expiring at expire, the zero time means never.
+// Updating a value counts as an access.
+func (c *Cache) AddWithExpire(key string, value policy.Value, expire time.Time) {
+	c.sketch.increment(key)
+	size := int64(len(key)) + int64(value.Len())
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		kv.seg.nbytes += size - kv.size
+		kv.value, kv.expire, kv.size = value, expire, size
+		c.hit(ele)
+	} else {
+		c.cache[key] = c.push(&c.window, &entry{key: key, value: value, expire: expire, size: size})
+	}
+	c.evict()
+}
+
+func (c *Cache) push(s *segment, kv *entry) *list.Element {
+	kv.seg = s
+	s.nbytes += kv.size
+	return s.ll.PushFront(kv)
+}
+
+func (c *Cache) move(ele *list.Element, to *segment) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.nbytes -= kv.size
+	c.cache[kv.key] = c.push(to, kv)
+}
+
+// hit promotes entries of probation to protected,
+// and demotes the least recent protected entries when it is full
+func (c *Cache) hit(ele *list.Element) {
+	kv := ele.Value.(*entry)
+	switch kv.seg {
+	case &c.window:
+		c.window.ll.MoveToFront(ele)
+	case &c.protected:
+		c.protected.ll.MoveToFront(ele)
+	case &c.probation:
+		c.move(ele, &c.protected)
+		for c.maxBytes != 0 && c.protected.nbytes > c.protected.maxBytes && c.protected.ll.Len() > 1 {
+			c.move(c.protected.ll.Back(), &c.probation)
+		}
+	}
+}
+
+func (c *Cache) mainBytes() int64 {
+	return c.probation.nbytes + c.protected.nbytes
+}
+
+// evict moves entries out of the window into main,
+// the admission filter decides who stays when main is full
+func (c *Cache) evict() {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.window.nbytes > c.window.maxBytes {
+		ele := c.window.ll.Back()
+		candidate := ele.Value.(*entry)
+		c.move(ele, &c.probation)
+		for c.mainBytes() > c.probation.maxBytes {
+			victim := c.probation.ll.Back()
+			if victim.Value.(*entry) == candidate {
+				// the candidate is the only one of probation, compare with protected
+				if victim = victim.Prev(); victim == nil {
+					victim = c.protected.ll.Back()
+				}
+			}
+			if victim == nil || c.sketch.estimate(candidate.key) <= c.sketch.estimate(victim.Value.(*entry).key) {
+				c.remove(c.cache[candidate.key], policy.Capacity)
+				break
+			}
+			c.remove(victim, policy.Capacity)
+		}
+	}
+	// updates grow main in place, without moving anything out of the window
+	for c.protected.nbytes > c.protected.maxBytes && c.protected.ll.Len() > 1 {
+		c.move(c.protected.ll.Back(), &c.probation)
+	}
+	for c.mainBytes() > c.probation.maxBytes {
+		victim := c.probation.ll.Back()
+		if victim == nil {
+			victim = c.protected.ll.Back()
+		}
+		c.remove(victim, policy.Capacity)
+	}
+}
+
+// Get look ups a key's value, expired entries are removed lazily
+func (c *Cache) Get(key string) (value policy.Value, ok bool) {
+	value, _, ok = c.GetWithExpire(key)
+	return
+}
+
+// GetWithExpire look ups a key's value and when it expires
+func (c *Cache) GetWithExpire(key string) (value policy.Value, expire time.Time, ok bool) {
+	c.sketch.increment(key)
+	ele, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	kv := ele.Value.(*entry)
+	if policy.IsExpired(kv.expire, c.now()) {
+		c.remove(ele, policy.Expired)
+		return nil, time.Time{}, false
+	}
+	c.hit(ele)
+	return kv.value, kv.expire, true
+}
+
+// Remove removes the key from the cache
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		c.remove(ele, policy.Removed)
+	}
+}
+
+func (c *Cache) remove(ele *list.Element, reason policy.EvictReason) {
+	kv := ele.Value.(*entry)
+	kv.seg.ll.Remove(ele)
+	kv.seg.nbytes -= kv.size
+	delete(c.cache, kv.key)
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// RemoveExpired removes all expired items and returns the number of them
+func (c *Cache) RemoveExpired() int {
+	n := 0
+	now := c.now()
+	for _, ele := range c.cache {
+		if policy.IsExpired(ele.Value.(*entry).expire, now) {
+			c.remove(ele, policy.Expired)
+			n++
+		}
+	}
+	return n
+}
+
+// Len the number of cache entries
+func (c *Cache) Len() int {
+	return len(c.cache)
+}
+
+// Bytes the number of bytes taken by the entries
+func (c *Cache) Bytes() int64 {
+	return c.window.nbytes + c.mainBytes()
+}
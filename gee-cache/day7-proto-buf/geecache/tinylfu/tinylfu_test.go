@@ -0,0 +1,103 @@
+package tinylfu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type String string
+
+func (d String) Len() int {
+	return len(d)
+}
+
+func TestGet(t *testing.T) {
+	lfu := New(int64(0), nil)
+	lfu.Add("key1", String("1234"))
+	if v, ok := lfu.Get("key1"); !ok || string(v.(String)) != "1234" {
+		t.Fatalf("cache hit key1=1234 failed")
+	}
+	if _, ok := lfu.Get("key2"); ok {
+		t.Fatalf("cache miss key2 failed")
+	}
+}
+
+func TestSketch(t *testing.T) {
+	s := newCMSketch(16)
+	for i := 0; i < 5; i++ {
+		s.increment("hot")
+	}
+	s.increment("cold")
+	if s.estimate("hot") < 5 || s.estimate("cold") < 1 || s.estimate("hot") <= s.estimate("cold") {
+		t.Fatalf("hot = %d, cold = %d", s.estimate("hot"), s.estimate("cold"))
+	}
+	s.reset()
+	if s.estimate("hot") < 2 || s.estimate("hot") > 3 {
+		t.Fatalf("hot = %d after reset", s.estimate("hot"))
+	}
+}
+
+func TestAdmission(t *testing.T) {
+	lfu := New(int64(1000), nil)
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("hot%d", i)
+		for j := 0; j < 5; j++ {
+			if _, ok := lfu.Get(key); !ok {
+				lfu.Add(key, String("0123456789"))
+			}
+		}
+	}
+	// one-hit wonders are not admitted in place of popular entries
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("scan%d", i)
+		if _, ok := lfu.Get(key); !ok {
+			lfu.Add(key, String("0123456789"))
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := lfu.Get(fmt.Sprintf("hot%d", i)); !ok {
+			t.Fatalf("hot%d should survive the scan", i)
+		}
+	}
+	if lfu.Bytes() > 1000 {
+		t.Fatalf("bytes = %d", lfu.Bytes())
+	}
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Now()
+	lfu := New(int64(0), nil)
+	lfu.now = func() time.Time { return now }
+	lfu.AddWithExpire("key1", String("1"), now.Add(time.Second))
+	lfu.AddWithExpire("key2", String("2"), now.Add(time.Second))
+	lfu.Add("key3", String("3"))
+	now = now.Add(time.Second)
+	if _, ok := lfu.Get("key1"); ok {
+		t.Fatalf("key1 should be expired")
+	}
+	if n := lfu.RemoveExpired(); n != 1 || lfu.Len() != 1 {
+		t.Fatalf("RemoveExpired removed %d items", n)
+	}
+}
+
+func TestUpdateLarger(t *testing.T) {
+	c := New(int64(1000), nil)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 20; i++ {
+			c.Add(fmt.Sprintf("key%d", i), String("0123456789"))
+			c.Get(fmt.Sprintf("key%d", i))
+		}
+	}
+	// the entries in probation and protected grow in place
+	for i := 0; i < 20; i++ {
+		c.Add(fmt.Sprintf("key%d", i), String(strings.Repeat("v", 400)))
+		if c.Bytes() > 1000 {
+			t.Fatalf("bytes = %d after updating key%d", c.Bytes(), i)
+		}
+	}
+	if c.protected.nbytes > c.protected.maxBytes && c.protected.ll.Len() > 1 {
+		t.Fatalf("protected = %d bytes", c.protected.nbytes)
+	}
+}
@@ -0,0 +1,89 @@
+package geecache
+
+import (
+	"fmt"
+	pb "geecache/geecachepb"
+	"io/ioutil"
+	"log"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// benchCluster starts 3 peers of the transport, and returns the picker of the first one
+// and keys owned by the others
+func benchCluster(b *testing.B, transport string) (PeerPicker, []string, func()) {
+	NewGroup("bench", 2<<20, GetterFunc(func(key string) ([]byte, error) {
+		return []byte("value of " + key), nil
+	}))
+	var addrs []string
+	var pickers []PeerPicker
+	var closers []func()
+	for i := 0; i < 3; i++ {
+		switch transport {
+		case "http":
+			server := httptest.NewUnstartedServer(nil)
+			addr := "http://" + server.Listener.Addr().String()
+			pool := NewHTTPPool(addr)
+			server.Config.Handler = pool
+			server.Start()
+			addrs = append(addrs, addr)
+			pickers = append(pickers, pool)
+			closers = append(closers, server.Close)
+		case "tcp":
+			pool, l := startTCPPool(b)
+			addrs = append(addrs, pool.self)
+			pickers = append(pickers, pool)
+			closers = append(closers, func() { l.Close() })
+		}
+	}
+	for _, p := range pickers {
+		switch p := p.(type) {
+		case *HTTPPool:
+			p.Set(addrs...)
+		case *TCPPool:
+			p.Set(addrs...)
+		}
+	}
+	var keys []string
+	for i := 0; len(keys) < 1000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if _, ok := pickers[0].PickPeer(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return pickers[0], keys, func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+}
+
+func BenchmarkTransport(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	for _, transport := range []string{"http", "tcp"} {
+		picker, keys, stop := benchCluster(b, transport)
+		get := func(i int) {
+			key := keys[i%len(keys)]
+			peer, _ := picker.PickPeer(key)
+			res := &pb.Response{}
+			if err := peer.Get(&pb.Request{Group: "bench", Key: key}, res); err != nil {
+				b.Error(err)
+			}
+		}
+		b.Run(transport, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				get(i)
+			}
+		})
+		b.Run(transport+"-parallel", func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					get(i)
+				}
+			})
+		})
+		stop()
+	}
+}
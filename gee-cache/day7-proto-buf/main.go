@@ -14,6 +14,7 @@ import (
 	"geecache"
 	"log"
 	"net/http"
+	"strings"
 )
 
 var db = map[string]string{
@@ -33,6 +34,19 @@ func createGroup() *geecache.Group {
 		}))
 }
 
+func startTCPCacheServer(addr string, addrs []string, gee *geecache.Group) {
+	// peers of the TCP transport are addressed without the scheme
+	addr = strings.TrimPrefix(addr, "http://")
+	for i := range addrs {
+		addrs[i] = strings.TrimPrefix(addrs[i], "http://")
+	}
+	peers := geecache.NewTCPPool(addr)
+	peers.Set(addrs...)
+	gee.RegisterPeers(peers)
+	log.Println("geecache is running at tcp://" + addr)
+	log.Fatal(peers.ListenAndServe())
+}
+
 func startCacheServer(addr string, addrs []string, gee *geecache.Group) {
 	peers := geecache.NewHTTPPool(addr)
 	peers.Set(addrs...)
@@ -45,7 +59,7 @@ func startAPIServer(apiAddr string, gee *geecache.Group) {
 	http.Handle("/api", http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			key := r.URL.Query().Get("key")
-			view, err := gee.Get(key)
+			view, err := gee.GetContext(r.Context(), key)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -62,8 +76,10 @@ func startAPIServer(apiAddr string, gee *geecache.Group) {
 func main() {
 	var port int
 	var api bool
+	var transport string
 	flag.IntVar(&port, "port", 8001, "Geecache server port")
 	flag.BoolVar(&api, "api", false, "Start a api server?")
+	flag.StringVar(&transport, "transport", "http", "Transport between peers, http or tcp")
 	flag.Parse()
 
 	apiAddr := "http://localhost:9999"
@@ -82,5 +98,9 @@ func main() {
 	if api {
 		go startAPIServer(apiAddr, gee)
 	}
+	if transport == "tcp" {
+		startTCPCacheServer(addrMap[port], addrs, gee)
+		return
+	}
 	startCacheServer(addrMap[port], addrs, gee)
 }
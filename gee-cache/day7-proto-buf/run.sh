@@ -1,10 +1,11 @@
 #!/bin/bash
 trap "rm server;kill 0" EXIT
 
+# TRANSPORT=tcp ./run.sh to use the TCP transport between peers
 go build -o server
-./server -port=8001 &
-./server -port=8002 &
-./server -port=8003 -api=1 &
+./server -port=8001 -transport=${TRANSPORT:-http} &
+./server -port=8002 -transport=${TRANSPORT:-http} &
+./server -port=8003 -transport=${TRANSPORT:-http} -api=1 &
 
 sleep 2
 echo ">>> start test"
@@ -0,0 +1,78 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// WrapH wraps a http.Handler as a HandlerFunc
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
+
+// WrapF wraps a http.HandlerFunc as a HandlerFunc
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		f(c.Writer, c.Req)
+	}
+}
+
+// Mount serves all methods of prefix and its sub paths by h,
+// the prefix is stripped from the path of the request, e.g.
+// Mount("/files", h) serves `/files/a.txt` as `/a.txt`
+func (group *RouterGroup) Mount(prefix string, h http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	absolutePrefix := group.prefix + prefix
+	handler := func(c *Context) {
+		h.ServeHTTP(c.Writer, stripPrefix(c.Req, absolutePrefix))
+	}
+	exact := prefix
+	if absolutePrefix == "" {
+		// Mount("/", h) of the engine, the empty pattern never matches
+		exact = "/"
+	}
+	group.Any(exact, handler)
+	group.Any(prefix+"/*filepath", handler)
+}
+
+// stripPrefix returns a shallow copy of req with prefix removed from the path
+func stripPrefix(req *http.Request, prefix string) *http.Request {
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Path = "/" + strings.TrimLeft(strings.TrimPrefix(req.URL.Path, prefix), "/")
+	if req.URL.RawPath != "" {
+		r.URL.RawPath = "/" + strings.TrimLeft(strings.TrimPrefix(req.URL.RawPath, prefix), "/")
+	}
+	return r
+}
+
+type contextKey struct{}
+
+// WrapMiddleware adapts a standard middleware to a HandlerFunc,
+// the rest handlers run as the next http.Handler of the middleware,
+// with the ResponseWriter and Request it passes. If the middleware
+// doesn't call next, the rest handlers are skipped.
+func WrapMiddleware(m func(http.Handler) http.Handler) HandlerFunc {
+	// the middleware is built once, the Context is passed through the request
+	h := m(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c := req.Context().Value(contextKey{}).(*Context)
+		c.Writer, c.Req = w, req
+		c.Next()
+	}))
+	return func(c *Context) {
+		w, req := c.Writer, c.Req
+		index := c.index
+		h.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), contextKey{}, c)))
+		if c.index == index {
+			// next is not called
+			c.index = len(c.handlers)
+		}
+		c.Writer, c.Req = w, req
+	}
+}
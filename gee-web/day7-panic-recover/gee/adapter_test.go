@@ -0,0 +1,103 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	r := New()
+	api := r.Group("/api")
+	api.Mount("/files", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Method + " " + req.URL.Path))
+	}))
+	r.GET("/wrap", WrapF(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("wrapped"))
+	}))
+
+	cases := map[string]string{
+		"/api/files":          "GET /",
+		"/api/files/a/b.txt":  "GET /a/b.txt",
+		"/api/files/hello.go": "GET /hello.go",
+		"/wrap":               "wrapped",
+	}
+	for path, body := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Body.String() != body {
+			t.Fatalf("%s: expect %q, got %q", path, body, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/files/a", nil))
+	if w.Body.String() != "DELETE /a" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestMountRoot(t *testing.T) {
+	r := New()
+	r.GET("/api/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+	r.Mount("/", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Method + " " + req.URL.Path))
+	}))
+
+	cases := map[string]string{
+		"/":           "GET /",
+		"/index.html": "GET /index.html",
+		"/a/b.txt":    "GET /a/b.txt",
+		"/api/ping":   "pong",
+	}
+	for path, body := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != body {
+			t.Fatalf("%s: expect %q, got %d %q", path, body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	type userKey struct{}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("X-User") == "" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Auth", "ok")
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), userKey{}, req.Header.Get("X-User"))))
+		})
+	}
+	var order []string
+	r := New()
+	r.Use(func(c *Context) {
+		order = append(order, "before")
+		c.Next()
+		order = append(order, "after")
+	}, WrapMiddleware(auth))
+	r.GET("/", func(c *Context) {
+		order = append(order, "handler")
+		c.String(http.StatusOK, "%v", c.Value(userKey{}))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-User", "geektutu")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "geektutu" || w.Header().Get("X-Auth") != "ok" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+	if len(order) != 3 || order[0] != "before" || order[1] != "handler" || order[2] != "after" {
+		t.Fatalf("unexpected order %v", order)
+	}
+
+	order = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusForbidden || len(order) != 2 {
+		t.Fatalf("handler should be skipped, got %d %v", w.Code, order)
+	}
+}
@@ -0,0 +1,127 @@
+package gee
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// immutableCacheControl is the Cache-Control of fingerprinted assets,
+// their URLs change with their content, so they never need revalidation
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// asset is a file of StaticAssets
+type asset struct {
+	file    string // path on disk
+	hash    string
+	modTime time.Time
+}
+
+// fingerprint inserts the hash before the extension, e.g. css/geektutu.3b5d5c3712.css
+func fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// hashAssets hashes all files under root, keyed by their slash separated relative paths
+func hashAssets(root string) (map[string]*asset, error) {
+	assets := make(map[string]*asset)
+	err := filepath.Walk(root, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		assets[filepath.ToSlash(rel)] = &asset{
+			file:    file,
+			hash:    hex.EncodeToString(h.Sum(nil))[:10],
+			modTime: info.ModTime(),
+		}
+		return nil
+	})
+	return assets, err
+}
+
+// StaticAssets serves files under root like Static, but with content-hashed URLs.
+// Files are hashed once here, restart the engine after changing them.
+// Call it after SetFuncMap and before LoadHTMLGlob, then `{{asset "css/geektutu.css"}}` in templates emits
+// the fingerprinted URL like `/assets/css/geektutu.3b5d5c3712.css`,
+// which is served with immutable cache headers.
+// Requests of the original names are still served, but revalidated on every use.
+func (group *RouterGroup) StaticAssets(relativePath string, root string) error {
+	assets, err := hashAssets(root)
+	if err != nil {
+		return err
+	}
+	prefix := path.Join(group.prefix, relativePath)
+	byFingerprint := make(map[string]*asset, len(assets))
+	urls := make(map[string]string, len(assets))
+	for name, a := range assets {
+		byFingerprint[fingerprint(name, a.hash)] = a
+		urls[name] = prefix + "/" + fingerprint(name, a.hash)
+	}
+
+	engine := group.engine
+	engine.mu.Lock()
+	if engine.assets == nil {
+		engine.assets = make(map[string]string)
+	}
+	for name, url := range urls {
+		engine.assets[name] = url
+	}
+	engine.mu.Unlock()
+	engine.addFuncs(template.FuncMap{"asset": engine.assetURL})
+
+	group.GET(path.Join(relativePath, "/*filepath"), func(c *Context) {
+		name := strings.TrimPrefix(c.Param("filepath"), "/")
+		a, immutable := byFingerprint[name]
+		if !immutable {
+			if a = assets[name]; a == nil {
+				c.Status(http.StatusNotFound)
+				return
+			}
+		}
+		f, err := os.Open(a.file)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		defer f.Close()
+		if immutable {
+			c.SetHeader("Cache-Control", immutableCacheControl)
+		} else {
+			c.SetHeader("Cache-Control", "no-cache")
+		}
+		c.SetHeader("ETag", `"`+a.hash+`"`)
+		http.ServeContent(c.Writer, c.Req, name, a.modTime, f)
+	})
+	return nil
+}
+
+// assetURL is the `asset` template func, unknown names are returned as they are
+func (engine *Engine) assetURL(name string) string {
+	engine.mu.RLock()
+	defer engine.mu.RUnlock()
+	if url, ok := engine.assets[strings.TrimPrefix(name, "/")]; ok {
+		return url
+	}
+	return name
+}
@@ -0,0 +1,94 @@
+package gee
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStaticAssets(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "static", "css"), 0755)
+	os.WriteFile(filepath.Join(dir, "static", "css", "geektutu.css"), []byte("p { color: orange; }"), 0644)
+	os.WriteFile(filepath.Join(dir, "index.tmpl"), []byte(`<link rel="stylesheet" href="{{asset "css/geektutu.css"}}">{{asset "missing.js"}}`), 0644)
+
+	r := New()
+	if err := r.StaticAssets("/assets", filepath.Join(dir, "static")); err != nil {
+		t.Fatal(err)
+	}
+	r.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.tmpl", nil) })
+
+	w := serve(r, "GET", "/", nil)
+	body := w.Body.String()
+	start := strings.Index(body, `href="`) + len(`href="`)
+	url := body[start : start+strings.IndexByte(body[start:], '"')]
+	if !strings.HasPrefix(url, "/assets/css/geektutu.") || !strings.HasSuffix(url, ".css") || url == "/assets/css/geektutu.css" {
+		t.Fatalf("asset url = %q", url)
+	}
+	if !strings.HasSuffix(body, "missing.js") {
+		t.Fatalf("unknown asset = %q", body)
+	}
+
+	w = serve(r, "GET", url, nil)
+	if w.Code != http.StatusOK || w.Body.String() != "p { color: orange; }" {
+		t.Fatalf("GET %s = %d %q", url, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != immutableCacheControl || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("headers = %v", w.Header())
+	}
+	if w = serve(r, "GET", url, map[string]string{"If-None-Match": w.Header().Get("ETag")}); w.Code != http.StatusNotModified {
+		t.Fatalf("revalidation = %d", w.Code)
+	}
+
+	w = serve(r, "GET", "/assets/css/geektutu.css", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("original name = %d %v", w.Code, w.Header())
+	}
+	if w = serve(r, "GET", "/assets/css/geektutu.0000000000.css", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("stale fingerprint = %d", w.Code)
+	}
+}
+
+func TestStaticAssetsFuncMap(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("alert(1)"), 0644)
+
+	r := New()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper, "lower": strings.ToLower})
+	// SetFuncMap replaces the funcs set before
+	funcs := template.FuncMap{"upper": strings.ToUpper}
+	r.SetFuncMap(funcs)
+	if err := r.StaticAssets("/assets", dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 1 {
+		t.Fatalf("funcs of SetFuncMap are modified: %v", funcs)
+	}
+	if _, ok := r.funcMap["lower"]; ok {
+		t.Fatal("SetFuncMap should replace the funcs")
+	}
+	for _, name := range []string{"upper", "asset"} {
+		if _, ok := r.funcMap[name]; !ok {
+			t.Fatalf("func %s is missing", name)
+		}
+	}
+
+	// asset is kept by SetFuncMap after StaticAssets
+	funcs = template.FuncMap{"lower": strings.ToLower}
+	r.SetFuncMap(funcs)
+	if len(funcs) != 1 {
+		t.Fatalf("funcs of SetFuncMap are modified: %v", funcs)
+	}
+	for _, name := range []string{"lower", "asset"} {
+		if _, ok := r.funcMap[name]; !ok {
+			t.Fatalf("func %s is missing after SetFuncMap", name)
+		}
+	}
+	if _, ok := r.funcMap["upper"]; ok {
+		t.Fatal("SetFuncMap should replace the funcs")
+	}
+}
@@ -0,0 +1,71 @@
+package gee
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AuthUserKey is the key of the authenticated user in Context
+const AuthUserKey = "user"
+
+// Accounts maps user names to passwords for BasicAuth
+type Accounts map[string]string
+
+// unauthorized aborts the request with 401 and the challenge of WWW-Authenticate
+func unauthorized(c *Context, challenge string, message string) {
+	c.SetHeader("WWW-Authenticate", challenge)
+	c.Fail(http.StatusUnauthorized, message)
+}
+
+// BasicAuth returns a HTTP Basic Authentication middleware,
+// the user name is stored in Context with AuthUserKey
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm is like BasicAuth with a custom realm,
+// "Authorization Required" is used if realm is empty
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	// compare digests, so that the time doesn't depend on the length of credentials
+	digests := make(map[string][sha256.Size]byte, len(accounts))
+	for user, password := range accounts {
+		digests[user] = sha256.Sum256([]byte(user + ":" + password))
+	}
+	return func(c *Context) {
+		user, ok := searchCredential(digests, c.Req.Header.Get("Authorization"))
+		if !ok {
+			unauthorized(c, challenge, "Unauthorized")
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
+
+func searchCredential(digests map[string][sha256.Size]byte, header string) (string, bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	credential, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", false
+	}
+	digest := sha256.Sum256(credential)
+	found, matched := "", 0
+	// visit all accounts to not leak which user exists
+	for user, expected := range digests {
+		if subtle.ConstantTimeCompare(digest[:], expected[:]) == 1 {
+			found, matched = user, 1
+		}
+	}
+	return found, matched == 1
+}
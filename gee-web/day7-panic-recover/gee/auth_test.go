@@ -0,0 +1,89 @@
+package gee
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := New()
+	r.Use(BasicAuth(Accounts{"geektutu": "123456"}))
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, c.MustGet(AuthUserKey).(string))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("geektutu", "123456")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	req.SetBasicAuth("geektutu", "654321")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Basic realm="Authorization Required"` {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
+
+func TestJWT(t *testing.T) {
+	now := time.Now().Unix()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsToken, _ := SignJWT("HS256", JWTClaims{"sub": "geektutu", "aud": []string{"gee"}, "exp": now + 60}, []byte("secret"))
+	rsToken, _ := SignJWT("RS256", JWTClaims{"sub": "geektutu", "iss": "geektutu.com"}, private)
+	expired, _ := SignJWT("HS256", JWTClaims{"sub": "geektutu", "exp": now - 60}, []byte("secret"))
+	future, _ := SignJWT("HS256", JWTClaims{"sub": "geektutu", "nbf": now + 60}, []byte("secret"))
+	// exp and nbf must be numbers when present
+	stringExp, _ := SignJWT("HS256", JWTClaims{"sub": "geektutu", "aud": "gee", "exp": "0"}, []byte("secret"))
+	nullExp, _ := SignJWT("HS256", JWTClaims{"sub": "geektutu", "aud": "gee", "exp": nil}, []byte("secret"))
+	stringNbf, _ := SignJWT("HS256", JWTClaims{"sub": "geektutu", "aud": "gee", "nbf": "0"}, []byte("secret"))
+
+	handler := func(c *Context) {
+		claims := c.MustGet(JWTClaimsKey).(JWTClaims)
+		c.String(http.StatusOK, claims["sub"].(string))
+	}
+	r := New()
+	r.GET("/hs", JWT(JWTConfig{Key: []byte("secret"), Audience: "gee", TokenLookup: "header:Authorization,query:token"}), handler)
+	r.GET("/rs", JWT(JWTConfig{Algorithm: "RS256", PublicKey: &private.PublicKey, Issuer: "geektutu.com"}), handler)
+
+	cases := []struct {
+		path, token string
+		code        int
+	}{
+		{"/hs", hsToken, http.StatusOK},
+		{"/hs?token=" + hsToken, "", http.StatusOK},
+		{"/rs", rsToken, http.StatusOK},
+		{"/rs", hsToken, http.StatusUnauthorized},
+		{"/hs", hsToken[:len(hsToken)-2], http.StatusUnauthorized},
+		{"/hs", expired, http.StatusUnauthorized},
+		{"/hs", future, http.StatusUnauthorized},
+		{"/hs", stringExp, http.StatusUnauthorized},
+		{"/hs", nullExp, http.StatusUnauthorized},
+		{"/hs", stringNbf, http.StatusUnauthorized},
+		{"/hs", "", http.StatusUnauthorized},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest("GET", cs.path, nil)
+		if cs.token != "" {
+			req.Header.Set("Authorization", "Bearer "+cs.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != cs.code {
+			t.Fatalf("%s: expect %d, got %d %s", cs.path, cs.code, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer ") {
+			t.Fatalf("%s: WWW-Authenticate should be set", cs.path)
+		}
+	}
+}
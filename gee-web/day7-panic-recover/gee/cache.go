@@ -0,0 +1,252 @@
+package gee
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response captured by the Cache middleware
+type CachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	ETag    string
+	Expires time.Time
+}
+
+// CacheStore stores cached responses, it must be safe for concurrent access
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+type cacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// MemoryCacheStore is a LRU CacheStore in memory
+type MemoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	cache      map[string]*list.Element
+}
+
+// NewMemoryCacheStore creates a MemoryCacheStore keeping at most maxEntries responses,
+// 0 means no limit
+func NewMemoryCacheStore(maxEntries int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		cache:      make(map[string]*list.Element),
+	}
+}
+
+func (s *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ele, ok := s.cache[key]; ok {
+		s.ll.MoveToFront(ele)
+		return ele.Value.(*cacheEntry).resp, true
+	}
+	return nil, false
+}
+
+func (s *MemoryCacheStore) Set(key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ele, ok := s.cache[key]; ok {
+		s.ll.MoveToFront(ele)
+		ele.Value.(*cacheEntry).resp = resp
+		return
+	}
+	s.cache[key] = s.ll.PushFront(&cacheEntry{key: key, resp: resp})
+	for s.maxEntries != 0 && s.ll.Len() > s.maxEntries {
+		ele := s.ll.Back()
+		s.ll.Remove(ele)
+		delete(s.cache, ele.Value.(*cacheEntry).key)
+	}
+}
+
+func (s *MemoryCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ele, ok := s.cache[key]; ok {
+		s.ll.Remove(ele)
+		delete(s.cache, key)
+	}
+}
+
+// CacheConfig configures the Cache middleware
+type CacheConfig struct {
+	TTL   time.Duration
+	Store CacheStore
+	// QueryParams are the query parameters in the cache key,
+	// the whole query string is used if it is nil
+	QueryParams []string
+	// Headers are the request headers in the cache key, e.g. Accept-Language
+	Headers []string
+}
+
+// cacheWriter buffers the response of the handler
+type cacheWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+}
+
+func (w *cacheWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+// Cache returns a middleware caching responses of GET and HEAD for ttl in store,
+// the cache key is the method, host, path and query string
+func Cache(ttl time.Duration, store CacheStore) HandlerFunc {
+	return CacheWithConfig(CacheConfig{TTL: ttl, Store: store})
+}
+
+// CacheWithConfig is like Cache with custom cache keys
+func CacheWithConfig(config CacheConfig) HandlerFunc {
+	if config.Store == nil {
+		config.Store = NewMemoryCacheStore(1000)
+	}
+	return func(c *Context) {
+		if c.Method != "GET" && c.Method != "HEAD" {
+			c.Next()
+			return
+		}
+		key := config.key(c)
+		cacheControl := c.Req.Header.Get("Cache-Control")
+		// no-cache asks for a fresh response, which refreshes the cache
+		if !strings.Contains(cacheControl, "no-cache") && !strings.Contains(cacheControl, "no-store") {
+			if resp, ok := config.Store.Get(key); ok {
+				if time.Now().Before(resp.Expires) {
+					c.Writer.Header().Set("X-Cache", "HIT")
+					writeCachedResponse(c, resp)
+					c.index = len(c.handlers)
+					return
+				}
+				config.Store.Delete(key)
+			}
+		}
+
+		w := c.Writer
+		cw := &cacheWriter{header: make(http.Header)}
+		func() {
+			// a panic downstream is answered by Recovery with the original writer
+			defer func() { c.Writer = w }()
+			c.Writer = cw
+			c.Next()
+		}()
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		resp := &CachedResponse{
+			Status:  cw.status,
+			Header:  cw.header,
+			Body:    cw.buf.Bytes(),
+			ETag:    cw.header.Get("ETag"),
+			Expires: time.Now().Add(config.TTL),
+		}
+		if resp.Status == http.StatusOK && resp.ETag == "" {
+			resp.ETag = strongETag(resp.Body)
+		}
+		if cacheable(resp, cacheControl) {
+			config.Store.Set(key, resp)
+		}
+		c.Writer.Header().Set("X-Cache", "MISS")
+		writeCachedResponse(c, resp)
+	}
+}
+
+func (config *CacheConfig) key(c *Context) string {
+	var b strings.Builder
+	b.WriteString(c.Method + " " + c.Req.Host + c.Path + "?")
+	if config.QueryParams == nil {
+		b.WriteString(c.Req.URL.RawQuery)
+	} else {
+		query := c.Req.URL.Query()
+		values := make(url.Values)
+		for _, name := range config.QueryParams {
+			if v, ok := query[name]; ok {
+				values[name] = v
+			}
+		}
+		// Encode sorts by key
+		b.WriteString(values.Encode())
+	}
+	headers := append([]string(nil), config.Headers...)
+	sort.Strings(headers)
+	for _, name := range headers {
+		b.WriteString("|" + name + "=" + c.Req.Header.Get(name))
+	}
+	return b.String()
+}
+
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func cacheable(resp *CachedResponse, requestCacheControl string) bool {
+	if resp.Status != http.StatusOK || strings.Contains(requestCacheControl, "no-store") {
+		return false
+	}
+	cacheControl := resp.Header.Get("Cache-Control")
+	return !strings.Contains(cacheControl, "no-store") && !strings.Contains(cacheControl, "private") &&
+		resp.Header.Get("Set-Cookie") == ""
+}
+
+// writeCachedResponse writes resp, 304 is returned if If-None-Match matches the ETag
+func writeCachedResponse(c *Context, resp *CachedResponse) {
+	header := c.Writer.Header()
+	for k, v := range resp.Header {
+		header[k] = v
+	}
+	if resp.ETag != "" {
+		header.Set("ETag", resp.ETag)
+		if etagMatch(c.Req.Header.Get("If-None-Match"), resp.ETag) {
+			header.Del("Content-Length")
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Status(resp.Status)
+	if c.Method != "HEAD" {
+		c.Writer.Write(resp.Body)
+	}
+}
+
+func etagMatch(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
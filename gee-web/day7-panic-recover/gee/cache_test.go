@@ -0,0 +1,82 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	r := New()
+	r.GET("/hello", CacheWithConfig(CacheConfig{TTL: 50 * time.Millisecond, QueryParams: []string{"name"}}), func(c *Context) {
+		calls++
+		c.SetHeader("X-Calls", "counted")
+		c.String(http.StatusOK, "hello %s", c.Query("name"))
+	})
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/hello?name=geektutu", nil)
+	etag := w.Header().Get("ETag")
+	if w.Body.String() != "hello geektutu" || w.Header().Get("X-Cache") != "MISS" || etag == "" {
+		t.Fatalf("unexpected response %s %v", w.Body.String(), w.Header())
+	}
+	// params not in QueryParams are ignored
+	w = get("/hello?name=geektutu&t=1", nil)
+	if w.Body.String() != "hello geektutu" || w.Header().Get("X-Cache") != "HIT" || w.Header().Get("X-Calls") != "counted" || calls != 1 {
+		t.Fatalf("cached response should be returned, %d calls", calls)
+	}
+	w = get("/hello?name=geektutu", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || calls != 1 {
+		t.Fatalf("expect 304, got %d", w.Code)
+	}
+	w = get("/hello?name=geektutu", http.Header{"Cache-Control": {"no-cache"}})
+	if w.Header().Get("X-Cache") != "MISS" || calls != 2 {
+		t.Fatal("no-cache should bypass the cache")
+	}
+	w = get("/hello?name=jack", nil)
+	if w.Body.String() != "hello jack" || calls != 3 {
+		t.Fatal("different query should not hit the cache")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if get("/hello?name=geektutu", nil); calls != 4 {
+		t.Fatal("expired response should not be returned")
+	}
+}
+
+func TestCachePanic(t *testing.T) {
+	r := New()
+	r.Use(Recovery(), Cache(time.Minute, nil))
+	r.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	w := serve(r, "GET", "/panic", nil)
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Internal Server Error") {
+		t.Fatalf("panic = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	s := NewMemoryCacheStore(2)
+	s.Set("k1", &CachedResponse{})
+	s.Set("k2", &CachedResponse{})
+	s.Get("k1")
+	s.Set("k3", &CachedResponse{})
+	if _, ok := s.Get("k2"); ok {
+		t.Fatal("k2 should be evicted")
+	}
+	if _, ok := s.Get("k1"); !ok {
+		t.Fatal("k1 should be kept")
+	}
+}
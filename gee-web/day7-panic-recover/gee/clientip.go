@@ -0,0 +1,137 @@
+package gee
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+)
+
+// Headers set by platforms, see Engine.TrustedPlatform
+const (
+	PlatformCloudflare      = "CF-Connecting-IP"
+	PlatformGoogleAppEngine = "X-Appengine-Remote-Addr"
+	PlatformFlyIO           = "Fly-Client-IP"
+)
+
+var defaultRemoteIPHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+
+// SetTrustedProxies sets the IPs or CIDRs of proxies whose headers are trusted by ClientIP,
+// no proxy is trusted by default
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return &net.ParseError{Type: "IP address", Text: proxy}
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	engine.trustedCIDRs = cidrs
+	return nil
+}
+
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, cidr := range engine.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP returns the IP of the peer, c.Req.RemoteAddr without the port
+func (c *Context) RemoteIP() string {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		return strings.TrimSpace(c.Req.RemoteAddr)
+	}
+	return ip
+}
+
+// ClientIP returns the real IP of the client. The header of Engine.TrustedPlatform is used
+// if it is set. Otherwise, if the peer is a trusted proxy, Engine.RemoteIPHeaders are walked
+// from right to left, the first IP not of a trusted proxy is the client.
+func (c *Context) ClientIP() string {
+	engine := c.engine
+	if engine == nil {
+		return c.RemoteIP()
+	}
+	if engine.TrustedPlatform != "" {
+		if ip := parseIP(c.Req.Header.Get(engine.TrustedPlatform)); ip != nil {
+			return ip.String()
+		}
+	}
+	remote := parseIP(c.RemoteIP())
+	if remote == nil || !engine.isTrustedProxy(remote) {
+		return c.RemoteIP()
+	}
+	headers := engine.RemoteIPHeaders
+	if headers == nil {
+		headers = defaultRemoteIPHeaders
+	}
+	for _, name := range headers {
+		var ips []string
+		for _, value := range c.Req.Header[textproto.CanonicalMIMEHeaderKey(name)] {
+			if strings.EqualFold(name, "Forwarded") {
+				ips = append(ips, parseForwarded(value)...)
+			} else {
+				ips = append(ips, strings.Split(value, ",")...)
+			}
+		}
+		if ip, ok := engine.walkIPs(ips); ok {
+			return ip
+		}
+	}
+	return remote.String()
+}
+
+// walkIPs returns the rightmost IP not of a trusted proxy
+func (engine *Engine) walkIPs(ips []string) (string, bool) {
+	for i := len(ips) - 1; i >= 0; i-- {
+		ip := parseIP(ips[i])
+		if ip == nil {
+			// unknown or obfuscated, the chain can't be trusted any more
+			return "", false
+		}
+		if i == 0 || !engine.isTrustedProxy(ip) {
+			return ip.String(), true
+		}
+	}
+	return "", false
+}
+
+// parseForwarded returns the `for` parameters of RFC 7239 Forwarded header, e.g.
+// `for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"`
+func parseForwarded(value string) []string {
+	var ips []string
+	for _, element := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+				ips = append(ips, strings.Trim(kv[1], `"`))
+			}
+		}
+	}
+	return ips
+}
+
+// parseIP parses IPs like `1.2.3.4`, `1.2.3.4:80`, `[::1]:80` and `::1`
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	return net.ParseIP(strings.Trim(s, "[]"))
+}
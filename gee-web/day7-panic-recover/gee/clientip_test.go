@@ -0,0 +1,52 @@
+package gee
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r := New()
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "bad"}); err == nil {
+		t.Fatal("invalid proxy should be rejected")
+	}
+
+	cases := []struct {
+		remote string
+		header map[string]string
+		ip     string
+	}{
+		{"1.1.1.1:80", map[string]string{"X-Forwarded-For": "2.2.2.2"}, "1.1.1.1"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "2.2.2.2, 3.3.3.3, 10.0.0.2"}, "3.3.3.3"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "10.0.0.3, 192.168.1.1"}, "10.0.0.3"},
+		{"192.168.1.1:80", map[string]string{"X-Real-IP": "4.4.4.4"}, "4.4.4.4"},
+		{"10.0.0.1:80", map[string]string{"Forwarded": `for=5.5.5.5;proto=https, for="[2001:db8::17]:4711"`}, "2001:db8::17"},
+		{"10.0.0.1:80", map[string]string{"X-Forwarded-For": "unknown"}, "10.0.0.1"},
+		{"[::1]:80", nil, "::1"},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = cs.remote
+		for k, v := range cs.header {
+			req.Header.Set(k, v)
+		}
+		c := newContext(httptest.NewRecorder(), req)
+		c.engine = r
+		if ip := c.ClientIP(); ip != cs.ip {
+			t.Fatalf("%s %v: expect %s, got %s", cs.remote, cs.header, cs.ip, ip)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "1.1.1.1:80"
+	req.Header.Set(PlatformCloudflare, "6.6.6.6")
+	c := newContext(httptest.NewRecorder(), req)
+	c.engine = r
+	r.TrustedPlatform = PlatformCloudflare
+	if c.ClientIP() != "6.6.6.6" || c.RemoteIP() != "1.1.1.1" {
+		t.Fatal("the header of trusted platform should be used")
+	}
+}
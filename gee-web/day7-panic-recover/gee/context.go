@@ -1,9 +1,15 @@
 package gee
 
 import (
-	"encoding/json"
-	"fmt"
+	"html/template"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type H map[string]interface{}
@@ -16,8 +22,20 @@ type Context struct {
 	Path   string
 	Method string
 	Params map[string]string
+	// query & form cache
+	queryCache url.Values
+	formCache  url.Values
+	// body of BodyLimit
+	limitedBody *limitedBody
 	// response info
 	StatusCode int
+	sameSite   http.SameSite
+	// key/value pairs shared by handlers of the request
+	Keys map[string]interface{}
+	// template funcs of the request
+	funcMap template.FuncMap
+	// route info
+	pattern string
 	// middleware
 	handlers []HandlerFunc
 	index    int
@@ -48,17 +66,225 @@ func (c *Context) Fail(code int, err string) {
 	c.JSON(code, H{"message": err})
 }
 
+// Set stores a new key/value pair for this context
+func (c *Context) Set(key string, value interface{}) {
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+// Get returns the value of the key set by Set
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet returns the value of the key, it panics if the key does not exist
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("Key \"" + key + "\" does not exist")
+}
+
+// Deadline implements context.Context with the context of the request
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
+}
+
+// Done is closed when the client disconnects or the deadline is exceeded
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+// Value returns the value of c.Set if key is a string set before,
+// otherwise the value of the request context
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	return c.Req.Context().Value(key)
+}
+
+// FullPath returns the matched route pattern, e.g. `/hello/:name`,
+// it is empty if no route is matched
+func (c *Context) FullPath() string {
+	return c.pattern
+}
+
 func (c *Context) Param(key string) string {
 	value, _ := c.Params[key]
 	return value
 }
 
-func (c *Context) PostForm(key string) string {
-	return c.Req.FormValue(key)
+func (c *Context) initQueryCache() {
+	if c.queryCache == nil {
+		c.queryCache = c.Req.URL.Query()
+	}
 }
 
 func (c *Context) Query(key string) string {
-	return c.Req.URL.Query().Get(key)
+	value, _ := c.GetQuery(key)
+	return value
+}
+
+// DefaultQuery returns defaultValue if the query key does not exist
+func (c *Context) DefaultQuery(key, defaultValue string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func (c *Context) GetQuery(key string) (string, bool) {
+	if values := c.QueryArray(key); len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// QueryArray returns all values of the query key, e.g. `?id=1&id=2`
+func (c *Context) QueryArray(key string) []string {
+	c.initQueryCache()
+	return c.queryCache[key]
+}
+
+// QueryMap returns the query values like `?ids[a]=1&ids[b]=2` as a map
+func (c *Context) QueryMap(key string) map[string]string {
+	c.initQueryCache()
+	return formMap(c.queryCache, key)
+}
+
+// initFormCache parses the urlencoded or multipart body,
+// files larger than engine.MaxMultipartMemory are stored on disk
+func (c *Context) initFormCache() {
+	if c.formCache != nil {
+		return
+	}
+	c.formCache = make(url.Values)
+	err := c.Req.ParseMultipartForm(c.maxMultipartMemory())
+	if err == http.ErrNotMultipart {
+		// errors of the urlencoded body are hidden behind ErrNotMultipart
+		err = nil
+	}
+	if c.bodyError(err) != nil {
+		return
+	}
+	c.formCache = c.Req.PostForm
+}
+
+// bodyError answers 413 if the body was read over the limit of BodyLimit,
+// the form parsers don't always keep ErrBodyTooLarge in err
+func (c *Context) bodyError(err error) error {
+	if c.limitedBody == nil || !c.limitedBody.exceeded {
+		return err
+	}
+	if c.StatusCode == 0 {
+		c.Fail(http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+	}
+	return ErrBodyTooLarge
+}
+
+func (c *Context) maxMultipartMemory() int64 {
+	if c.engine == nil {
+		return defaultMultipartMemory
+	}
+	return c.engine.MaxMultipartMemory
+}
+
+// PostForm returns the value of the key from the urlencoded or multipart body
+func (c *Context) PostForm(key string) string {
+	value, _ := c.GetPostForm(key)
+	return value
+}
+
+// DefaultPostForm returns defaultValue if the form key does not exist
+func (c *Context) DefaultPostForm(key, defaultValue string) string {
+	if value, ok := c.GetPostForm(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+func (c *Context) GetPostForm(key string) (string, bool) {
+	if values := c.PostFormArray(key); len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+func (c *Context) PostFormArray(key string) []string {
+	c.initFormCache()
+	return c.formCache[key]
+}
+
+func (c *Context) PostFormMap(key string) map[string]string {
+	c.initFormCache()
+	return formMap(c.formCache, key)
+}
+
+// formMap collects values of keys like `key[sub]`
+func formMap(values url.Values, key string) map[string]string {
+	dict := make(map[string]string)
+	for k, v := range values {
+		if i := strings.IndexByte(k, '['); i > 0 && k[:i] == key {
+			if j := strings.IndexByte(k[i+1:], ']'); j > 0 && len(v) > 0 {
+				dict[k[i+1:i+1+j]] = v[0]
+			}
+		}
+	}
+	return dict
+}
+
+// MultipartForm returns the parsed multipart form, including file uploads
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+		return c.Req.MultipartForm, c.bodyError(err)
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile returns the first file for the provided form key
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.Req.MultipartForm == nil {
+		if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+			return nil, c.bodyError(err)
+		}
+	}
+	f, fh, err := c.Req.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, nil
+}
+
+// SaveUploadedFile saves the uploaded file to dst
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, src)
+	return err
 }
 
 func (c *Context) Status(code int) {
@@ -71,31 +297,50 @@ func (c *Context) SetHeader(key string, value string) {
 }
 
 func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
-	c.Status(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
+	c.Render(code, StringRender{Format: format, Data: values})
 }
 
 func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), 500)
-	}
+	c.Render(code, JSONRender{Data: obj})
 }
 
 func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	c.Writer.Write(data)
+	c.Render(code, DataRender{Data: data})
 }
 
 // HTML template render
 // refer https://golang.org/pkg/html/template/
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(500, err.Error())
+	engine := c.engine
+	if c.funcMap == nil || engine.htmlClones == nil {
+		c.Render(code, HTMLRender{Template: engine.htmlTemplates, Name: name, Data: data})
+		return
+	}
+	// per-request funcs are set on a clone, clones are pooled instead of cloned per request
+	clones := engine.htmlClones
+	v := clones.Get()
+	tmpl, ok := v.(*template.Template)
+	if !ok {
+		c.Fail(500, v.(error).Error())
+		return
+	}
+	tmpl.Funcs(c.funcMap)
+	c.Render(code, HTMLRender{Template: tmpl, Name: name, Data: data})
+	// reset the funcs of this request, e.g. its CSRF token, before reuse
+	reset := make(template.FuncMap, len(c.funcMap))
+	for name := range c.funcMap {
+		if fn, ok := engine.htmlFuncs[name]; ok {
+			reset[name] = fn
+		}
+	}
+	clones.Put(tmpl.Funcs(reset))
+}
+
+// SetTemplateFunc sets a template func for HTML of this request only,
+// the func must be known when templates are loaded, e.g. `csrfField`
+func (c *Context) SetTemplateFunc(name string, fn interface{}) {
+	if c.funcMap == nil {
+		c.funcMap = make(template.FuncMap)
 	}
+	c.funcMap[name] = fn
 }
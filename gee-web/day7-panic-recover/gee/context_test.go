@@ -0,0 +1,193 @@
+package gee
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?id=1&id=2&ids[a]=x&ids[b]=y", nil)
+	c := newContext(httptest.NewRecorder(), req)
+	if c.Query("id") != "1" || len(c.QueryArray("id")) != 2 {
+		t.Fatal("failed to get query id")
+	}
+	if c.DefaultQuery("name", "geektutu") != "geektutu" {
+		t.Fatal("default value should be returned")
+	}
+	if m := c.QueryMap("ids"); len(m) != 2 || m["a"] != "x" || m["b"] != "y" {
+		t.Fatalf("failed to get query map, got %v", m)
+	}
+}
+
+func newUploadRequest(t *testing.T, content string) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("name", "geektutu")
+	fw, err := mw.CreateFormFile("file", "hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write([]byte(content))
+	_ = mw.Close()
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestFormFile(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "upload", "hello.txt")
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		if c.PostForm("name") != "geektutu" {
+			t.Fatal("failed to get post form")
+		}
+		file, err := c.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.SaveUploadedFile(file, dst); err != nil {
+			t.Fatal(err)
+		}
+		c.String(http.StatusOK, file.Filename)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, "hello gee"))
+	if w.Code != http.StatusOK || w.Body.String() != "hello.txt" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if data, _ := ioutil.ReadFile(dst); string(data) != "hello gee" {
+		t.Fatalf("unexpected saved file %q", data)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	r := New()
+	r.POST("/upload", BodyLimit(64), func(c *Context) {
+		if _, err := c.FormFile("file"); err != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, newUploadRequest(t, strings.Repeat("a", 1024)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", w.Code)
+	}
+
+	// unknown content length
+	req := newUploadRequest(t, strings.Repeat("a", 1024))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", w.Code)
+	}
+}
+
+func TestBodyLimitPostForm(t *testing.T) {
+	r := New()
+	r.POST("/login", BodyLimit(64), func(c *Context) {
+		// the handler ignores the empty form and writes anyway
+		c.String(http.StatusOK, "hello %s", c.PostForm("username"))
+	})
+
+	body := "username=" + strings.Repeat("a", 1024)
+	req := httptest.NewRequest("POST", "/login", ioutil.NopCloser(strings.NewReader(body)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expect 413, got %d", w.Code)
+	}
+}
+
+func TestSecureCookie(t *testing.T) {
+	r := New()
+	r.SetCookieKeys([]byte("old-key"))
+	r.GET("/set", func(c *Context) {
+		c.SetSignedCookie("user", "geektutu", 3600, "/", "", false, true)
+		_ = c.SetEncryptedCookie("token", "secret", 3600, "/", "", false, true)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/set", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 2 || strings.Contains(cookies[1].Value, "secret") {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	// rotate keys, cookies set by the old key are still valid
+	r.SetCookieKeys([]byte("new-key"), []byte("old-key"))
+	r.GET("/get", func(c *Context) {
+		user, err1 := c.SignedCookie("user")
+		token, err2 := c.EncryptedCookie("token")
+		if err1 != nil || err2 != nil {
+			c.String(http.StatusUnauthorized, "invalid")
+			return
+		}
+		c.String(http.StatusOK, "%s:%s", user, token)
+	})
+	req := httptest.NewRequest("GET", "/get", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "geektutu:secret" {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+
+	// tampered cookie
+	req = httptest.NewRequest("GET", "/get", nil)
+	req.AddCookie(&http.Cookie{Name: "user", Value: "Z2Vla3R1dHU.aGFja2Vk"})
+	req.AddCookie(cookies[1])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatal("tampered cookie should be rejected")
+	}
+}
+
+func TestHTMLTemplateFunc(t *testing.T) {
+	dir := t.TempDir()
+	_ = ioutil.WriteFile(filepath.Join(dir, "token.tmpl"), []byte(`[{{ csrfToken }}]`), 0644)
+
+	r := New()
+	r.LoadHTMLGlob(filepath.Join(dir, "*"))
+	r.GET("/token/:token", func(c *Context) {
+		c.SetTemplateFunc("csrfToken", func() string { return c.Param("token") })
+		c.HTML(http.StatusOK, "token.tmpl", nil)
+	})
+	r.GET("/other", func(c *Context) {
+		// funcs of other requests are never rendered
+		c.SetTemplateFunc("csrfField", func() string { return "" })
+		c.HTML(http.StatusOK, "token.tmpl", nil)
+	})
+
+	done := make(chan bool)
+	for i := 0; i < 8; i++ {
+		go func(token string) {
+			defer func() { done <- true }()
+			for j := 0; j < 20; j++ {
+				if body := serve(r, "GET", "/token/"+token, nil).Body.String(); body != "["+token+"]" {
+					t.Errorf("body = %q, want [%s]", body, token)
+					return
+				}
+			}
+		}(strings.Repeat("x", i+1))
+	}
+	for i := 0; i < 8; i++ {
+		<-done
+	}
+	if body := serve(r, "GET", "/other", nil).Body.String(); body != "[]" {
+		t.Fatalf("body = %q", body)
+	}
+}
@@ -0,0 +1,184 @@
+package gee
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidCookie is returned when a signed or encrypted cookie can't be verified
+var ErrInvalidCookie = errors.New("gee: invalid cookie")
+
+// SetSameSite sets the SameSite attribute of cookies set by SetCookie
+func (c *Context) SetSameSite(sameSite http.SameSite) {
+	c.sameSite = sameSite
+}
+
+// SetCookie adds a Set-Cookie header to the response,
+// maxAge < 0 deletes the cookie, maxAge = 0 makes it a session cookie
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Cookie returns the unescaped value of the named cookie
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// SetSignedCookie sets a cookie signed by the keys of Engine.SetCookieKeys,
+// the value is readable by the client but can't be modified
+func (c *Context) SetSignedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	c.SetCookie(name, c.secureCookie().Sign(name, value), maxAge, path, domain, secure, httpOnly)
+}
+
+// SignedCookie returns the value of a cookie set by SetSignedCookie
+func (c *Context) SignedCookie(name string) (string, error) {
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return c.secureCookie().Verify(name, value)
+}
+
+// SetEncryptedCookie sets a cookie encrypted by the keys of Engine.SetCookieKeys
+func (c *Context) SetEncryptedCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) error {
+	encrypted, err := c.secureCookie().Encrypt(name, value)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(name, encrypted, maxAge, path, domain, secure, httpOnly)
+	return nil
+}
+
+// EncryptedCookie returns the value of a cookie set by SetEncryptedCookie
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return c.secureCookie().Decrypt(name, value)
+}
+
+func (c *Context) secureCookie() *SecureCookie {
+	if c.engine == nil || c.engine.secureCookie == nil {
+		panic("gee: cookie keys are not set, call Engine.SetCookieKeys first")
+	}
+	return c.engine.secureCookie
+}
+
+// SetCookieKeys sets keys for signed and encrypted cookies,
+// the first key is used to sign and encrypt, all of them are used to verify and decrypt,
+// so keys can be rotated by prepending a new one
+func (engine *Engine) SetCookieKeys(keys ...[]byte) {
+	engine.secureCookie = NewSecureCookie(keys...)
+}
+
+// SecureCookie signs cookies with HMAC-SHA256 and encrypts cookies with AES-GCM
+type SecureCookie struct {
+	keys [][]byte
+	aead []cipher.AEAD
+}
+
+// NewSecureCookie creates a SecureCookie, keys are like Engine.SetCookieKeys
+func NewSecureCookie(keys ...[]byte) *SecureCookie {
+	if len(keys) == 0 {
+		panic("gee: at least one cookie key is required")
+	}
+	s := &SecureCookie{keys: keys}
+	for _, key := range keys {
+		// derive a 256 bits AES key from a key of any length
+		sum := sha256.Sum256(key)
+		block, _ := aes.NewCipher(sum[:])
+		aead, _ := cipher.NewGCM(block)
+		s.aead = append(s.aead, aead)
+	}
+	return s
+}
+
+func mac(key []byte, name, value string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(name + "|" + value))
+	return h.Sum(nil)
+}
+
+// Sign returns `base64(value).base64(mac)`, the name is signed as well
+// so that the value can't be moved to another cookie
+func (s *SecureCookie) Sign(name, value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(value)) + "." +
+		base64.RawURLEncoding.EncodeToString(mac(s.keys[0], name, value))
+}
+
+// Verify returns the value of a signed cookie
+func (s *SecureCookie) Verify(name, signed string) (string, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", ErrInvalidCookie
+	}
+	value, err := base64.RawURLEncoding.DecodeString(signed[:i])
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signed[i+1:])
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range s.keys {
+		if hmac.Equal(sum, mac(key, name, string(value))) {
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// Encrypt returns `base64(nonce|ciphertext)`, the name is authenticated as well
+func (s *SecureCookie) Encrypt(name, value string) (string, error) {
+	aead := s.aead[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the value of an encrypted cookie
+func (s *SecureCookie) Decrypt(name, encrypted string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, aead := range s.aead {
+		size := aead.NonceSize()
+		if len(data) < size {
+			return "", ErrInvalidCookie
+		}
+		if value, err := aead.Open(nil, data[:size], data[size:], []byte(name)); err == nil {
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
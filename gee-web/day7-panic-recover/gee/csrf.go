@@ -0,0 +1,133 @@
+package gee
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+// CSRFKey is the key of the CSRF token in Context
+const CSRFKey = "csrf_token"
+
+// csrfFuncMap are placeholders of the template funcs set by CSRF
+var csrfFuncMap = template.FuncMap{
+	"csrfField": func() template.HTML { return "" },
+	"csrfToken": func() string { return "" },
+}
+
+// CSRFStore keeps the CSRF token of a client, e.g. in the session
+type CSRFStore interface {
+	GetToken(c *Context) string
+	SaveToken(c *Context, token string) error
+}
+
+// CSRFConfig configures the CSRF middleware
+type CSRFConfig struct {
+	// Store keeps the token, the double submit cookie is used if it is nil
+	Store CSRFStore
+	// CookieName, CookiePath and Secure are options of the double submit cookie,
+	// "_csrf" and "/" by default
+	CookieName string
+	CookiePath string
+	Secure     bool
+	// FormField and Header are where the token is submitted,
+	// "_csrf" and "X-CSRF-Token" by default
+	FormField string
+	Header    string
+	// ExemptRoutes are route patterns not checked, e.g. "/api/*filepath"
+	ExemptRoutes []string
+	// Skip is called to exempt other requests
+	Skip func(c *Context) bool
+}
+
+// cookieCSRFStore is the double submit cookie, the token is kept in a SameSite cookie
+// and the form or header must submit the same value
+type cookieCSRFStore struct {
+	name   string
+	path   string
+	secure bool
+}
+
+func (s *cookieCSRFStore) GetToken(c *Context) string {
+	token, _ := c.Cookie(s.name)
+	return token
+}
+
+func (s *cookieCSRFStore) SaveToken(c *Context, token string) error {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(s.name, token, 0, s.path, "", s.secure, true)
+	return nil
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CSRF returns a middleware protecting unsafe methods against cross-site request forgery,
+// the token is available by c.Get(CSRFKey) and template funcs csrfField & csrfToken
+func CSRF(config CSRFConfig) HandlerFunc {
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.FormField == "" {
+		config.FormField = "_csrf"
+	}
+	if config.Header == "" {
+		config.Header = "X-CSRF-Token"
+	}
+	store := config.Store
+	if store == nil {
+		store = &cookieCSRFStore{name: config.CookieName, path: config.CookiePath, secure: config.Secure}
+	}
+	exempt := make(map[string]bool)
+	for _, pattern := range config.ExemptRoutes {
+		exempt[pattern] = true
+	}
+
+	return func(c *Context) {
+		if exempt[c.FullPath()] || (config.Skip != nil && config.Skip(c)) {
+			c.Next()
+			return
+		}
+		token := store.GetToken(c)
+		switch c.Method {
+		case "GET", "HEAD", "OPTIONS", "TRACE":
+		default:
+			submitted := c.Req.Header.Get(config.Header)
+			if submitted == "" {
+				submitted = c.PostForm(config.FormField)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(submitted)) != 1 {
+				c.Fail(http.StatusForbidden, "invalid csrf token")
+				return
+			}
+		}
+		if token == "" {
+			var err error
+			if token, err = newCSRFToken(); err == nil {
+				err = store.SaveToken(c, token)
+			}
+			if err != nil {
+				c.Fail(http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		field := template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(config.FormField) +
+			`" value="` + template.HTMLEscapeString(token) + `">`)
+		c.Set(CSRFKey, token)
+		c.SetTemplateFunc("csrfField", func() template.HTML { return field })
+		c.SetTemplateFunc("csrfToken", func() string { return token })
+		c.Next()
+	}
+}
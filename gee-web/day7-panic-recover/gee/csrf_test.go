@@ -0,0 +1,63 @@
+package gee
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	dir := t.TempDir()
+	_ = ioutil.WriteFile(filepath.Join(dir, "form.tmpl"), []byte(`<form>{{ csrfField }}</form>`), 0644)
+
+	r := New()
+	r.Use(CSRF(CSRFConfig{ExemptRoutes: []string{"/api/hook"}}))
+	r.LoadHTMLGlob(filepath.Join(dir, "*"))
+	r.GET("/form", func(c *Context) {
+		c.HTML(http.StatusOK, "form.tmpl", nil)
+	})
+	r.POST("/form", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.POST("/api/hook", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/form", nil))
+	matches := regexp.MustCompile(`name="_csrf" value="([^"]+)"`).FindStringSubmatch(w.Body.String())
+	if len(matches) != 2 {
+		t.Fatalf("csrf field should be rendered, got %s", w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+
+	post := func(path, token string, withCookie bool) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(url.Values{"_csrf": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if withCookie {
+			for _, cookie := range cookies {
+				req.AddCookie(cookie)
+			}
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post("/form", matches[1], true); code != http.StatusOK {
+		t.Fatalf("valid token should pass, got %d", code)
+	}
+	if code := post("/form", "forged", true); code != http.StatusForbidden {
+		t.Fatalf("invalid token should be rejected, got %d", code)
+	}
+	if code := post("/form", matches[1], false); code != http.StatusForbidden {
+		t.Fatalf("token without cookie should be rejected, got %d", code)
+	}
+	if code := post("/api/hook", "", false); code != http.StatusOK {
+		t.Fatalf("exempt route should pass, got %d", code)
+	}
+}
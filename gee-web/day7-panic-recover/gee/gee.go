@@ -3,9 +3,11 @@ package gee
 import (
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 )
 
 // HandlerFunc defines the request handler used by gee
@@ -17,22 +19,56 @@ type (
 		prefix      string
 		middlewares []HandlerFunc // support middleware
 		parent      *RouterGroup  // support nesting
+		handlers    []HandlerFunc // prepended to the routes of the group, for prefixes with params
 		engine      *Engine       // all groups share a Engine instance
+		router      *router       // router of the host the group belongs to
 	}
 
 	Engine struct {
 		*RouterGroup
 		router        *router
+		mu            sync.RWMutex       // guards groups, hosts, middlewares, funcMap and assets
 		groups        []*RouterGroup     // store all groups
+		hosts         []*host            // virtual hosts
 		htmlTemplates *template.Template // for html render
+		htmlBase      *template.Template // never executed, cloned for per-request funcs
+		htmlFuncs     template.FuncMap   // funcs of htmlBase, to reset the clones
+		htmlClones    *sync.Pool         // clones of htmlBase, reused by requests with funcs
 		funcMap       template.FuncMap   // for html render
+		secureCookie  *SecureCookie      // for signed & encrypted cookies
+		trustedCIDRs  []*net.IPNet       // for client ip
+		assets        map[string]string  // fingerprinted URLs of StaticAssets
+
+		// MaxMultipartMemory is the maximum bytes of a multipart body kept in memory,
+		// the rest is stored in temporary files
+		MaxMultipartMemory int64
+		// TrustedPlatform is the header of the client IP set by the platform,
+		// e.g. PlatformCloudflare, it is trusted without checking the peer
+		TrustedPlatform string
+		// RemoteIPHeaders are the headers of client IPs set by trusted proxies,
+		// Forwarded, X-Forwarded-For and X-Real-IP by default
+		RemoteIPHeaders []string
+		// SecureJSONPrefix is the prefix of Context.SecureJSON
+		SecureJSONPrefix string
+		// MethodOverride routes POST requests as the method of their
+		// `X-HTTP-Method-Override` header, `_method` query or `_method` field
+		// of an urlencoded body, so that HTML forms can send PUT, PATCH and DELETE.
+		MethodOverride bool
 	}
 )
 
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "CONNECT", "TRACE"}
+
 // Engine 的构造函数
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
-	engine.RouterGroup = &RouterGroup{engine: engine}
+	engine := &Engine{
+		router:             newRouter(),
+		MaxMultipartMemory: defaultMultipartMemory,
+		SecureJSONPrefix:   "while(1);",
+	}
+	engine.RouterGroup = &RouterGroup{engine: engine, router: engine.router}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
 	return engine
 }
@@ -50,33 +86,85 @@ func Default() *Engine {
 func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	engine := group.engine
 	newGroup := &RouterGroup{
-		prefix: group.prefix + prefix,
-		parent: group,
-		engine: engine,
+		prefix:   group.prefix + prefix,
+		parent:   group,
+		engine:   engine,
+		router:   group.router,
+		handlers: group.handlers,
 	}
+	engine.mu.Lock()
 	engine.groups = append(engine.groups, newGroup)
+	engine.mu.Unlock()
 	return newGroup
 }
 
 // Use is defined to add middleware to the group
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
+	group.engine.mu.Lock()
+	defer group.engine.mu.Unlock()
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+func (group *RouterGroup) addRoute(method string, comp string, handlers []HandlerFunc) *RouteInfo {
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	if len(group.handlers) > 0 {
+		handlers = append(append([]HandlerFunc(nil), group.handlers...), handlers...)
+	}
+	log.Printf("Route %4s - %s", method, group.router.host+pattern)
+	info := &RouteInfo{Method: method, Pattern: pattern}
+	group.router.addRouteInfo(info, handlers)
+	return info
+}
+
+// RemoveRoute removes the route of method and pattern,
+// it is safe to add and remove routes while the engine is serving.
+// false is returned if the route doesn't exist.
+func (group *RouterGroup) RemoveRoute(method string, pattern string) bool {
+	pattern = group.prefix + pattern
+	if !group.router.removeRoute(method, pattern) {
+		return false
+	}
+	log.Printf("Route %4s - %s removed", method, group.router.host+pattern)
+	return true
 }
 
-// GET defines the method to add GET request
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+// Handle registers handlers for the method and pattern
+func (group *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) *RouteInfo {
+	return group.addRoute(method, pattern, handlers)
+}
+
+// Any registers handlers for all common methods
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) {
+	for _, method := range anyMethods {
+		group.addRoute(method, pattern, handlers)
+	}
+}
+
+// GET defines the method to add GET request,
+// handlers before the last one act as route middlewares.
+// The returned RouteInfo describes the route for OpenAPI.
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *RouteInfo {
+	return group.addRoute("GET", pattern, handlers)
 }
 
 // POST defines the method to add POST request
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *RouteInfo {
+	return group.addRoute("POST", pattern, handlers)
+}
+
+// PUT defines the method to add PUT request
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *RouteInfo {
+	return group.addRoute("PUT", pattern, handlers)
+}
+
+// PATCH defines the method to add PATCH request
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *RouteInfo {
+	return group.addRoute("PATCH", pattern, handlers)
+}
+
+// DELETE defines the method to add DELETE request
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *RouteInfo {
+	return group.addRoute("DELETE", pattern, handlers)
 }
 
 // create static handler
@@ -103,13 +191,59 @@ func (group *RouterGroup) Static(relativePath string, root string) {
 	group.GET(urlPattern, handler)
 }
 
-// for custom render function
+// for custom render function,
+// it replaces the funcs set before, except `asset` of StaticAssets unless funcMap has its own
 func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	engine.funcMap = funcMap
+	if _, ok := funcMap["asset"]; !ok && engine.assets != nil {
+		// the map is copied so that the one passed is never modified
+		engine.funcMap = make(template.FuncMap, len(funcMap)+1)
+		for name, fn := range funcMap {
+			engine.funcMap[name] = fn
+		}
+		engine.funcMap["asset"] = engine.assetURL
+	}
+}
+
+// addFuncs adds funcs to the funcs of SetFuncMap,
+// the map is copied so that the one passed to SetFuncMap is never modified
+func (engine *Engine) addFuncs(funcs template.FuncMap) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	funcMap := make(template.FuncMap, len(engine.funcMap)+len(funcs))
+	for name, fn := range engine.funcMap {
+		funcMap[name] = fn
+	}
+	for name, fn := range funcs {
+		funcMap[name] = fn
+	}
 	engine.funcMap = funcMap
 }
 
 func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+	funcMap := template.FuncMap{}
+	// placeholders of per-request funcs, replaced by Context.SetTemplateFunc
+	for name, fn := range csrfFuncMap {
+		funcMap[name] = fn
+	}
+	engine.mu.RLock()
+	for name, fn := range engine.funcMap {
+		funcMap[name] = fn
+	}
+	engine.mu.RUnlock()
+	base := template.Must(template.New("").Funcs(funcMap).ParseGlob(pattern))
+	engine.htmlBase, engine.htmlFuncs = base, funcMap
+	engine.htmlTemplates = template.Must(base.Clone())
+	engine.htmlClones = &sync.Pool{New: func() interface{} {
+		// a template can't be cloned after execution, the base never executes
+		tmpl, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		return tmpl
+	}}
 }
 
 // Run defines the method to start a http server
@@ -118,14 +252,24 @@ func (engine *Engine) Run(addr string) (err error) {
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	engine.mu.RLock()
+	r, hostParams := engine.matchHost(req.Host)
 	var middlewares []HandlerFunc
 	for _, group := range engine.groups {
+		if group != engine.RouterGroup && group.router != r {
+			continue
+		}
 		if strings.HasPrefix(req.URL.Path, group.prefix) {
 			middlewares = append(middlewares, group.middlewares...)
 		}
 	}
+	engine.mu.RUnlock()
+	if engine.MethodOverride {
+		overrideMethod(req)
+	}
 	c := newContext(w, req)
 	c.handlers = middlewares
 	c.engine = engine
-	engine.router.handle(c)
+	c.Params = hostParams
+	r.handle(c)
 }
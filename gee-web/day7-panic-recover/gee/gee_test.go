@@ -1,6 +1,12 @@
 package gee
 
-import "testing"
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
 
 func TestNestedGroup(t *testing.T) {
 	r := New()
@@ -14,3 +20,79 @@ func TestNestedGroup(t *testing.T) {
 		t.Fatal("v2 prefix should be /v1/v2")
 	}
 }
+
+func TestHost(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+	api := r.Host("api.example.com")
+	api.Use(func(c *Context) {
+		c.SetHeader("X-Host", "api")
+	})
+	api.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "api")
+	})
+	tenant := r.Host(":tenant.example.com")
+	tenant.GET("/hello/:name", func(c *Context) {
+		c.String(http.StatusOK, "%s-%s", c.Param("tenant"), c.Param("name"))
+	})
+	if r.Host("api.example.com") != api {
+		t.Fatal("the same host should return the same group")
+	}
+
+	cases := []struct {
+		host, path, body, header string
+	}{
+		{"api.example.com:9999", "/", "api", "api"},
+		{"API.example.com", "/", "api", "api"},
+		{"geek.example.com", "/hello/tutu", "geek-tutu", ""},
+		{"www.geektutu.com", "/", "default", ""},
+	}
+	for _, cs := range cases {
+		req := httptest.NewRequest("GET", cs.path, nil)
+		req.Host = cs.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != cs.body || w.Header().Get("X-Host") != cs.header {
+			t.Fatalf("%s%s: got body %q, X-Host %q", cs.host, cs.path, w.Body.String(), w.Header().Get("X-Host"))
+		}
+	}
+}
+
+func TestRoutesAtRuntime(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "index")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			pattern := fmt.Sprintf("/plugin%d/:name", i)
+			r.GET(pattern, func(c *Context) {
+				c.String(http.StatusOK, c.Param("name"))
+			})
+			r.RemoveRoute("GET", pattern)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			if w.Body.String() != "index" {
+				t.Error("index should always be served")
+			}
+		}
+	}()
+	wg.Wait()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/plugin1/geektutu", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatal("removed route should not be served")
+	}
+}
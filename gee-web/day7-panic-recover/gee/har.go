@@ -0,0 +1,171 @@
+package gee
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+)
+
+// HAR 1.2 types, see http://www.softwareishard.com/blog/har-12-spec/
+type (
+	HAR struct {
+		Log HARLog `json:"log"`
+	}
+	HARLog struct {
+		Version string     `json:"version"`
+		Creator HARCreator `json:"creator"`
+		Entries []HAREntry `json:"entries"`
+	}
+	HARCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	HAREntry struct {
+		StartedDateTime string      `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         HARRequest  `json:"request"`
+		Response        HARResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         HARTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+	HARRequest struct {
+		Method      string      `json:"method"`
+		URL         string      `json:"url"`
+		HTTPVersion string      `json:"httpVersion"`
+		Cookies     []HARPair   `json:"cookies"`
+		Headers     []HARPair   `json:"headers"`
+		QueryString []HARPair   `json:"queryString"`
+		PostData    *HARContent `json:"postData,omitempty"`
+		HeadersSize int         `json:"headersSize"`
+		BodySize    int         `json:"bodySize"`
+	}
+	HARResponse struct {
+		Status      int        `json:"status"`
+		StatusText  string     `json:"statusText"`
+		HTTPVersion string     `json:"httpVersion"`
+		Cookies     []HARPair  `json:"cookies"`
+		Headers     []HARPair  `json:"headers"`
+		Content     HARContent `json:"content"`
+		RedirectURL string     `json:"redirectURL"`
+		HeadersSize int        `json:"headersSize"`
+		BodySize    int        `json:"bodySize"`
+	}
+	HARPair struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	HARContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+	}
+	HARTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// HAR exports the recorded requests as HAR 1.2,
+// the route of each entry is kept in its comment
+func (rec *Recorder) HAR() *HAR {
+	har := &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "gee", Version: "1.0"},
+		Entries: []HAREntry{},
+	}}
+	for _, record := range rec.Records() {
+		har.Log.Entries = append(har.Log.Entries, record.harEntry())
+	}
+	return har
+}
+
+func (record *Record) harEntry() HAREntry {
+	ms := float64(record.Duration) / 1e6
+	u, _ := url.Parse(record.URL)
+	if u != nil && u.Host == "" {
+		u.Scheme, u.Host = "http", record.Host
+	}
+	request := HARRequest{
+		Method:      record.Method,
+		URL:         u.String(),
+		HTTPVersion: record.Proto,
+		Cookies:     harCookies((&http.Request{Header: record.RequestHeader}).Cookies()),
+		Headers:     harHeaders(record.RequestHeader),
+		QueryString: []HARPair{},
+		HeadersSize: -1,
+		BodySize:    len(record.RequestBody),
+	}
+	if u != nil {
+		request.QueryString = harValues(u.Query())
+	}
+	if len(record.RequestBody) > 0 {
+		content := harContent(record.RequestHeader.Get("Content-Type"), record.RequestBody)
+		request.PostData = &content
+	}
+	response := HARResponse{
+		Status:      record.Status,
+		StatusText:  http.StatusText(record.Status),
+		HTTPVersion: record.Proto,
+		Cookies:     harCookies((&http.Response{Header: record.ResponseHeader}).Cookies()),
+		Headers:     harHeaders(record.ResponseHeader),
+		Content:     harContent(record.ResponseHeader.Get("Content-Type"), record.ResponseBody),
+		RedirectURL: record.ResponseHeader.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(record.ResponseBody),
+	}
+	return HAREntry{
+		StartedDateTime: record.Start.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            ms,
+		Request:         request,
+		Response:        response,
+		Timings:         HARTimings{Wait: ms},
+		Comment:         record.Route,
+	}
+}
+
+func harHeaders(header http.Header) []HARPair {
+	pairs := []HARPair{}
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, HARPair{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+func harValues(values url.Values) []HARPair {
+	pairs := []HARPair{}
+	for name, vs := range values {
+		for _, value := range vs {
+			pairs = append(pairs, HARPair{Name: name, Value: value})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+func harCookies(cookies []*http.Cookie) []HARPair {
+	pairs := []HARPair{}
+	for _, cookie := range cookies {
+		pairs = append(pairs, HARPair{Name: cookie.Name, Value: cookie.Value})
+	}
+	return pairs
+}
+
+// harContent keeps text bodies as they are and base64 encodes binary ones
+func harContent(mimeType string, body []byte) HARContent {
+	content := HARContent{Size: len(body), MimeType: mimeType}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
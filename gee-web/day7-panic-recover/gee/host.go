@@ -0,0 +1,107 @@
+package gee
+
+import (
+	"net"
+	"strings"
+)
+
+// host is a virtual host with its own router,
+// pattern is like `api.example.com` or `:tenant.example.com`
+type host struct {
+	pattern string
+	labels  []string
+	group   *RouterGroup
+	isWild  bool
+}
+
+func newHost(pattern string, group *RouterGroup) *host {
+	labels := strings.Split(strings.ToLower(pattern), ".")
+	isWild := false
+	for _, label := range labels {
+		if label != "" && label[0] == ':' {
+			isWild = true
+		}
+	}
+	return &host{pattern: pattern, labels: labels, group: group, isWild: isWild}
+}
+
+// match reports whether hostname matches the host pattern,
+// the values of `:param` labels are returned as params
+func (h *host) match(hostname string) (map[string]string, bool) {
+	labels := strings.Split(hostname, ".")
+	if len(labels) != len(h.labels) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, label := range h.labels {
+		if label != "" && label[0] == ':' {
+			if labels[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[label[1:]] = labels[i]
+			continue
+		}
+		if label != labels[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// hostname strips the port of req.Host
+func hostname(hostport string) string {
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		hostport = h
+	}
+	return strings.ToLower(strings.TrimSuffix(hostport, "."))
+}
+
+// Host returns the root group of the virtual host,
+// routes and middlewares added to it only serve requests of the host.
+// Middlewares of the engine itself are applied to all hosts.
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+	for _, h := range engine.hosts {
+		if h.pattern == pattern {
+			return h.group
+		}
+	}
+	group := &RouterGroup{engine: engine, router: newRouter()}
+	group.router.host = pattern
+	engine.groups = append(engine.groups, group)
+	engine.hosts = append(engine.hosts, newHost(pattern, group))
+	return group
+}
+
+// matchHost finds the router of the request host,
+// exact hosts take precedence over wildcard ones,
+// it falls back to the default host if nothing matches,
+// engine.mu must be held
+func (engine *Engine) matchHost(hostport string) (*router, map[string]string) {
+	if len(engine.hosts) == 0 {
+		return engine.router, nil
+	}
+	name := hostname(hostport)
+	var wild *host
+	var wildParams map[string]string
+	for _, h := range engine.hosts {
+		params, ok := h.match(name)
+		if !ok {
+			continue
+		}
+		if !h.isWild {
+			return h.group.router, nil
+		}
+		if wild == nil {
+			wild, wildParams = h, params
+		}
+	}
+	if wild != nil {
+		return wild.group.router, wildParams
+	}
+	return engine.router, nil
+}
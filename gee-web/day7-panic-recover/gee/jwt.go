@@ -0,0 +1,274 @@
+package gee
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWTClaimsKey is the default key of the verified claims in Context
+const JWTClaimsKey = "jwt_claims"
+
+var (
+	ErrTokenMissing   = errors.New("gee: token is missing")
+	ErrTokenMalformed = errors.New("gee: token is malformed")
+	ErrTokenSignature = errors.New("gee: token signature is invalid")
+	ErrTokenExpired   = errors.New("gee: token is expired")
+	ErrTokenNotValid  = errors.New("gee: token is not valid yet")
+	ErrTokenAudience  = errors.New("gee: token audience is invalid")
+	ErrTokenIssuer    = errors.New("gee: token issuer is invalid")
+)
+
+// JWTClaims is the payload of a JSON Web Token
+type JWTClaims map[string]interface{}
+
+// JWTConfig configures the JWT middleware
+type JWTConfig struct {
+	// Algorithm is HS256 or RS256, HS256 by default
+	Algorithm string
+	// Key is the secret of HS256
+	Key []byte
+	// PublicKey verifies RS256 tokens
+	PublicKey *rsa.PublicKey
+	// Audience and Issuer are checked against aud and iss if not empty
+	Audience string
+	Issuer   string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	// TokenLookup is a comma separated list of `source:name` where the token is looked up,
+	// sources are header, cookie and query, "header:Authorization" by default
+	TokenLookup string
+	// Realm of the WWW-Authenticate header
+	Realm string
+	// ContextKey is the key of claims in Context, JWTClaimsKey by default
+	ContextKey string
+}
+
+// JWT returns a middleware verifying bearer tokens,
+// the claims are stored in Context with config.ContextKey
+func JWT(config JWTConfig) HandlerFunc {
+	if config.Algorithm == "" {
+		config.Algorithm = "HS256"
+	}
+	if config.TokenLookup == "" {
+		config.TokenLookup = "header:Authorization"
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = JWTClaimsKey
+	}
+	if config.Realm == "" {
+		config.Realm = "Authorization Required"
+	}
+	return func(c *Context) {
+		challenge := "Bearer realm=" + strconv.Quote(config.Realm)
+		token := lookupToken(c, config.TokenLookup)
+		if token == "" {
+			unauthorized(c, challenge, ErrTokenMissing.Error())
+			return
+		}
+		claims, err := ParseJWT(token, &config)
+		if err != nil {
+			challenge += `, error="invalid_token", error_description=` + strconv.Quote(err.Error())
+			unauthorized(c, challenge, err.Error())
+			return
+		}
+		c.Set(config.ContextKey, claims)
+		c.Next()
+	}
+}
+
+func lookupToken(c *Context, lookup string) string {
+	for _, source := range strings.Split(lookup, ",") {
+		parts := strings.SplitN(strings.TrimSpace(source), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var token string
+		switch parts[0] {
+		case "header":
+			token = c.Req.Header.Get(parts[1])
+			if strings.EqualFold(parts[1], "Authorization") {
+				if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+					token = token[7:]
+				} else {
+					token = ""
+				}
+			}
+		case "cookie":
+			token, _ = c.Cookie(parts[1])
+		case "query":
+			token = c.Query(parts[1])
+		}
+		if token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+func segment(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// SignJWT returns a signed token of claims,
+// key is []byte for HS256 and *rsa.PrivateKey for RS256
+func SignJWT(algorithm string, claims JWTClaims, key interface{}) (string, error) {
+	header, err := segment(map[string]string{"alg": algorithm, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := segment(claims)
+	if err != nil {
+		return "", err
+	}
+	signing := header + "." + payload
+	var sig []byte
+	switch algorithm {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", errors.New("gee: HS256 requires a []byte key")
+		}
+		sig = hs256(secret, signing)
+	case "RS256":
+		private, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return "", errors.New("gee: RS256 requires a *rsa.PrivateKey key")
+		}
+		sum := sha256.Sum256([]byte(signing))
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, sum[:]); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("gee: unsupported algorithm " + algorithm)
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func hs256(secret []byte, signing string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(signing))
+	return h.Sum(nil)
+}
+
+// ParseJWT verifies the signature and claims of token
+func ParseJWT(token string, config *JWTConfig) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+	// the algorithm is decided by config, never by the token
+	if header.Alg != config.Algorithm {
+		return nil, ErrTokenSignature
+	}
+	signing := parts[0] + "." + parts[1]
+	switch config.Algorithm {
+	case "HS256":
+		if len(config.Key) == 0 || !hmac.Equal(sig, hs256(config.Key, signing)) {
+			return nil, ErrTokenSignature
+		}
+	case "RS256":
+		sum := sha256.Sum256([]byte(signing))
+		if config.PublicKey == nil || rsa.VerifyPKCS1v15(config.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+			return nil, ErrTokenSignature
+		}
+	default:
+		return nil, ErrTokenSignature
+	}
+
+	var claims JWTClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, claims.validate(config)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrTokenMalformed
+	}
+	return nil
+}
+
+func (claims JWTClaims) validate(config *JWTConfig) error {
+	now := time.Now()
+	exp, err := claims.numericDate("exp")
+	if err != nil {
+		return err
+	}
+	if exp != nil && now.After(exp.Add(config.Leeway)) {
+		return ErrTokenExpired
+	}
+	nbf, err := claims.numericDate("nbf")
+	if err != nil {
+		return err
+	}
+	if nbf != nil && now.Add(config.Leeway).Before(*nbf) {
+		return ErrTokenNotValid
+	}
+	if config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != config.Issuer {
+			return ErrTokenIssuer
+		}
+	}
+	if config.Audience != "" && !claims.hasAudience(config.Audience) {
+		return ErrTokenAudience
+	}
+	return nil
+}
+
+// numericDate returns the time of the claim, nil if it is absent,
+// claims like `"exp":"0"` or `"exp":null` are malformed rather than skipped
+func (claims JWTClaims) numericDate(name string) (*time.Time, error) {
+	v, ok := claims[name]
+	if !ok {
+		return nil, nil
+	}
+	seconds, ok := v.(float64)
+	if !ok {
+		return nil, ErrTokenMalformed
+	}
+	t := time.Unix(int64(seconds), 0)
+	return &t, nil
+}
+
+// hasAudience checks aud, which is either a string or an array of strings
+func (claims JWTClaims) hasAudience(audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if s, _ := a.(string); s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package gee
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrBodyTooLarge is returned when reading a body over the limit of BodyLimit
+var ErrBodyTooLarge = errors.New("gee: request body too large")
+
+// limitedBody is like http.MaxBytesReader,
+// and remembers whether the limit is exceeded
+type limitedBody struct {
+	rc       io.ReadCloser
+	n        int64 // remaining bytes
+	exceeded bool
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.rc.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		return n, err
+	}
+	n = int(l.n)
+	l.n = 0
+	l.exceeded = true
+	return n, ErrBodyTooLarge
+}
+
+func (l *limitedBody) Close() error {
+	return l.rc.Close()
+}
+
+// BodyLimit limits the size of request body to n bytes,
+// 413 is returned if the body is larger than that,
+// also when the form getters of Context hit the limit and the handler writes anyway
+func BodyLimit(n int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Req.ContentLength > n {
+			c.Fail(http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+			return
+		}
+		body := &limitedBody{rc: c.Req.Body, n: n}
+		c.Req.Body = body
+		c.limitedBody = body
+		c.Next()
+		// the handler failed to read the body and wrote nothing
+		if body.exceeded && c.StatusCode == 0 {
+			c.Fail(http.StatusRequestEntityTooLarge, "Request Entity Too Large")
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package gee
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the latency histogram buckets in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// unmatchedRoute is the route label of requests not matching any route,
+// so that raw paths don't blow up the number of series
+const unmatchedRoute = "NOT_FOUND"
+
+type metricKey struct {
+	method, route, status string
+}
+
+type histogram struct {
+	counts []uint64 // counts of each bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Metrics collects per-route request counters, latency histograms and in-flight gauges,
+// and exposes them in Prometheus text format
+type Metrics struct {
+	// Namespace prefixes metric names, "gee" by default
+	Namespace string
+	// Buckets of the latency histogram, DefaultBuckets by default
+	Buckets []float64
+
+	mu        sync.Mutex
+	requests  map[metricKey]uint64
+	latencies map[metricKey]*histogram
+	inFlight  map[metricKey]int64 // status is empty
+}
+
+// NewMetrics creates a Metrics with the default namespace and buckets
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Namespace: "gee",
+		Buckets:   DefaultBuckets,
+		requests:  make(map[metricKey]uint64),
+		latencies: make(map[metricKey]*histogram),
+		inFlight:  make(map[metricKey]int64),
+	}
+}
+
+// statusWriter records the status code written to the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Middleware returns the middleware recording metrics of requests
+func (m *Metrics) Middleware() HandlerFunc {
+	return func(c *Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		flight := metricKey{method: c.Method, route: route}
+		m.mu.Lock()
+		m.inFlight[flight]++
+		m.mu.Unlock()
+
+		w := &statusWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		start := time.Now()
+		defer func() {
+			elapsed := time.Since(start).Seconds()
+			err := recover()
+			status := w.status
+			if err != nil {
+				// the panic is recorded and left to Recovery
+				status = http.StatusInternalServerError
+			} else if status == 0 {
+				status = http.StatusOK
+			}
+			c.Writer = w.ResponseWriter
+			m.observe(flight, strconv.Itoa(status), elapsed)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		c.Next()
+	}
+}
+
+func (m *Metrics) observe(flight metricKey, status string, elapsed float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight[flight]--
+	key := metricKey{method: flight.method, route: flight.route, status: status}
+	m.requests[key]++
+	h, ok := m.latencies[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.Buckets))}
+		m.latencies[key] = h
+	}
+	h.count++
+	h.sum += elapsed
+	for i, bound := range m.Buckets {
+		if elapsed <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+}
+
+// Handler returns a handler exposing the metrics in Prometheus text format
+func (m *Metrics) Handler() HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Data(http.StatusOK, m.Export())
+	}
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (k metricKey) labels(extra string) string {
+	s := fmt.Sprintf(`method="%s",route="%s"`, labelReplacer.Replace(k.method), labelReplacer.Replace(k.route))
+	if k.status != "" {
+		s += `,status="` + k.status + `"`
+	}
+	if extra != "" {
+		s += "," + extra
+	}
+	return "{" + s + "}"
+}
+
+func sortedKeys(keys []metricKey) []metricKey {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Export returns the metrics in Prometheus text format
+func (m *Metrics) Export() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var buf bytes.Buffer
+	name := m.Namespace + "_http_requests_total"
+	fmt.Fprintf(&buf, "# HELP %s Total number of HTTP requests.\n# TYPE %s counter\n", name, name)
+	keys := make([]metricKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedKeys(keys) {
+		fmt.Fprintf(&buf, "%s%s %d\n", name, k.labels(""), m.requests[k])
+	}
+
+	name = m.Namespace + "_http_request_duration_seconds"
+	fmt.Fprintf(&buf, "# HELP %s Latency of HTTP requests.\n# TYPE %s histogram\n", name, name)
+	for _, k := range keys {
+		h := m.latencies[k]
+		var cumulative uint64
+		for i, bound := range m.Buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, k.labels(`le="`+formatFloat(bound)+`"`), cumulative)
+		}
+		fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, k.labels(`le="+Inf"`), h.count)
+		fmt.Fprintf(&buf, "%s_sum%s %s\n", name, k.labels(""), formatFloat(h.sum))
+		fmt.Fprintf(&buf, "%s_count%s %d\n", name, k.labels(""), h.count)
+	}
+
+	name = m.Namespace + "_http_requests_in_flight"
+	fmt.Fprintf(&buf, "# HELP %s Number of HTTP requests being served.\n# TYPE %s gauge\n", name, name)
+	keys = keys[:0]
+	for k := range m.inFlight {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedKeys(keys) {
+		fmt.Fprintf(&buf, "%s%s %d\n", name, k.labels(""), m.inFlight[k])
+	}
+	return buf.Bytes()
+}
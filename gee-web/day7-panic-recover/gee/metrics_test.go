@@ -0,0 +1,41 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	r := New()
+	r.Use(Recovery(), m.Middleware())
+	r.GET("/hello/:name", func(c *Context) {
+		c.String(http.StatusOK, "hello %s", c.Param("name"))
+	})
+	r.GET("/panic", func(c *Context) {
+		panic("oops")
+	})
+	r.GET("/metrics", m.Handler())
+
+	for _, path := range []string{"/hello/geektutu", "/hello/jack", "/panic", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, line := range []string{
+		`gee_http_requests_total{method="GET",route="/hello/:name",status="200"} 2`,
+		`gee_http_requests_total{method="GET",route="/panic",status="500"} 1`,
+		`gee_http_requests_total{method="GET",route="NOT_FOUND",status="404"} 1`,
+		`gee_http_request_duration_seconds_bucket{method="GET",route="/hello/:name",status="200",le="+Inf"} 2`,
+		`gee_http_request_duration_seconds_count{method="GET",route="/hello/:name",status="200"} 2`,
+		`gee_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		`gee_http_requests_in_flight{method="GET",route="/hello/:name"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Fatalf("%s not found in\n%s", line, body)
+		}
+	}
+}
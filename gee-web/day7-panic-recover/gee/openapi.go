@@ -0,0 +1,329 @@
+package gee
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteInfo describes a route, it is returned by route registration
+// to add the metadata of OpenAPI, e.g.
+//
+//	r.POST("/users", createUser).Summary("create a user").Request(User{}).Response(201, User{})
+type RouteInfo struct {
+	Method  string
+	Pattern string
+
+	summary     string
+	description string
+	tags        []string
+	request     interface{}
+	responses   map[int]interface{}
+}
+
+// Summary sets the summary and the optional description of the route
+func (info *RouteInfo) Summary(summary string, description ...string) *RouteInfo {
+	info.summary = summary
+	info.description = strings.Join(description, "\n")
+	return info
+}
+
+// Tags groups the route in the docs
+func (info *RouteInfo) Tags(tags ...string) *RouteInfo {
+	info.tags = append(info.tags, tags...)
+	return info
+}
+
+// Request sets a value of the JSON request body type, e.g. User{} or &User{}
+func (info *RouteInfo) Request(v interface{}) *RouteInfo {
+	info.request = v
+	return info
+}
+
+// Response sets a value of the JSON response body type of the status code,
+// v can be nil for responses without a body
+func (info *RouteInfo) Response(code int, v interface{}) *RouteInfo {
+	if info.responses == nil {
+		info.responses = make(map[int]interface{})
+	}
+	info.responses[code] = v
+	return info
+}
+
+// OpenAPIInfo is the info object of the document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// schemaRegistry reflects Go types into components/schemas
+type schemaRegistry struct {
+	schemas map[string]H
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (s *schemaRegistry) schema(t reflect.Type) H {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return H{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return H{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return H{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return H{"type": "number"}
+	case reflect.String:
+		return H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return H{"type": "string", "format": "byte"}
+		}
+		return H{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return H{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := s.schemas[name]; !ok {
+			// register first for recursive types
+			s.schemas[name] = H{}
+			s.schemas[name] = s.structSchema(t)
+		}
+		return H{"$ref": "#/components/schemas/" + name}
+	}
+	return H{}
+}
+
+// schemaName qualifies the name of t with its package, e.g. `net.url.URL`,
+// so that types of the same name from different packages don't collide
+func schemaName(t reflect.Type) string {
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+	return strings.Replace(t.PkgPath(), "/", ".", -1) + "." + t.Name()
+}
+
+func (s *schemaRegistry) structSchema(t reflect.Type) H {
+	properties := H{}
+	var required []string
+	s.fields(t, properties, &required, true)
+	schema := H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields adds the fields of t to properties, fields of embedded structs
+// are flattened like encoding/json, the shallower field wins
+func (s *schemaRegistry) fields(t reflect.Type, properties H, required *[]string, needed bool) {
+	type embedded struct {
+		t      reflect.Type
+		needed bool
+	}
+	var embeds []embedded
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embeds = append(embeds, embedded{ft, needed && f.Type.Kind() != reflect.Ptr})
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := properties[name]; ok {
+			continue
+		}
+		omitempty := false
+		if hasTag {
+			for _, opt := range strings.Split(tag, ",")[1:] {
+				omitempty = omitempty || opt == "omitempty"
+			}
+		}
+		properties[name] = s.schema(f.Type)
+		if needed && !omitempty && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+	for _, e := range embeds {
+		s.fields(e.t, properties, required, e.needed)
+	}
+}
+
+// openAPIPath converts `/users/:id/*filepath` into `/users/{id}/{filepath}`
+func openAPIPath(pattern string) (string, []string) {
+	var names []string
+	parts := parsePattern(pattern)
+	for i, part := range parts {
+		if part[0] == ':' || part[0] == '*' {
+			names = append(names, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return "/" + strings.Join(parts, "/"), names
+}
+
+func (s *schemaRegistry) operation(info *RouteInfo, params []string) H {
+	op := H{}
+	if info.summary != "" {
+		op["summary"] = info.summary
+	}
+	if info.description != "" {
+		op["description"] = info.description
+	}
+	if len(info.tags) > 0 {
+		op["tags"] = info.tags
+	}
+	if len(params) > 0 {
+		var parameters []H
+		for _, name := range params {
+			parameters = append(parameters, H{"name": name, "in": "path", "required": true, "schema": H{"type": "string"}})
+		}
+		op["parameters"] = parameters
+	}
+	if info.request != nil {
+		op["requestBody"] = H{
+			"required": true,
+			"content":  H{MIMEJSON: H{"schema": s.schema(reflect.TypeOf(info.request))}},
+		}
+	}
+	responses := H{}
+	for code, v := range info.responses {
+		description := http.StatusText(code)
+		if description == "" {
+			// description is required, custom codes have no status text
+			description = "Response " + strconv.Itoa(code)
+		}
+		resp := H{"description": description}
+		if v != nil {
+			resp["content"] = H{MIMEJSON: H{"schema": s.schema(reflect.TypeOf(v))}}
+		}
+		responses[strconv.Itoa(code)] = resp
+	}
+	if len(responses) == 0 {
+		responses["200"] = H{"description": http.StatusText(http.StatusOK)}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// OpenAPI generates the OpenAPI 3 document of the routes of the group's host,
+// path parameters are derived from `:param` and `*wildcard` segments
+func (group *RouterGroup) OpenAPI(info OpenAPIInfo) H {
+	s := &schemaRegistry{schemas: make(map[string]H)}
+	paths := H{}
+	table := group.router.load()
+	methods := make([]string, 0, len(table.roots))
+	for method := range table.roots {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		if method == http.MethodConnect {
+			// not an operation of OpenAPI path items, e.g. routes of Any
+			continue
+		}
+		nodes := make([]*node, 0)
+		table.roots[method].travel(&nodes)
+		for _, n := range nodes {
+			path, params := openAPIPath(n.pattern)
+			item, ok := paths[path].(H)
+			if !ok {
+				item = H{}
+				paths[path] = item
+			}
+			item[strings.ToLower(method)] = s.operation(n.info, params)
+		}
+	}
+	doc := H{
+		"openapi": "3.0.3",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(s.schemas) > 0 {
+		doc["components"] = H{"schemas": s.schemas}
+	}
+	return doc
+}
+
+// ServeOpenAPI serves the document at `path/openapi.json` and a docs page at path
+func (group *RouterGroup) ServeOpenAPI(path string, info OpenAPIInfo) {
+	path = strings.TrimSuffix(path, "/")
+	group.GET(path+"/openapi.json", func(c *Context) {
+		c.JSON(http.StatusOK, group.OpenAPI(info))
+	})
+	group.GET(path, func(c *Context) {
+		c.SetHeader("Content-Type", MIMEHTML+"; charset=utf-8")
+		c.Data(http.StatusOK, []byte(strings.Replace(docsPage, "{{spec}}", group.prefix+path+"/openapi.json", 1)))
+	})
+}
+
+// docsPage lists the operations of the document without any external resource
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API Docs</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 960px; }
+.op { border: 1px solid #ddd; border-radius: 4px; margin: 8px 0; padding: 8px; }
+.method { display: inline-block; width: 70px; font-weight: bold; text-transform: uppercase; }
+pre { background: #f6f8fa; padding: 8px; overflow: auto; }
+</style>
+</head>
+<body>
+<h1 id="title"></h1>
+<div id="ops"></div>
+<script>
+fetch("{{spec}}").then(function (resp) { return resp.json(); }).then(function (doc) {
+  document.getElementById("title").textContent = doc.info.title + " " + doc.info.version;
+  var ops = document.getElementById("ops");
+  Object.keys(doc.paths).sort().forEach(function (path) {
+    Object.keys(doc.paths[path]).forEach(function (method) {
+      var op = doc.paths[path][method];
+      var div = document.createElement("details");
+      div.className = "op";
+      var summary = document.createElement("summary");
+      summary.innerHTML = '<span class="method"></span><code></code> <span></span>';
+      summary.children[0].textContent = method;
+      summary.children[1].textContent = path;
+      summary.children[2].textContent = op.summary || "";
+      var pre = document.createElement("pre");
+      pre.textContent = JSON.stringify(op, null, 2);
+      div.appendChild(summary);
+      div.appendChild(pre);
+      ops.appendChild(div);
+    });
+  });
+});
+</script>
+</body>
+</html>
+`
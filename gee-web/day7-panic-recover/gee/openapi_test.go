@@ -0,0 +1,136 @@
+package gee
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type apiUser struct {
+	ID      int               `json:"id"`
+	Name    string            `json:"name"`
+	Email   string            `json:"email,omitempty"`
+	Created time.Time         `json:"created"`
+	Friends []*apiUser        `json:"friends,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	secret  string
+}
+
+type apiBase struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type apiMeta struct {
+	Version int `json:"version"`
+}
+
+// URL has the same name as net/url.URL
+type URL struct {
+	Link string `json:"link"`
+}
+
+type apiPost struct {
+	apiBase
+	*apiMeta
+	ID    string `json:"id"` // shadows apiBase.ID
+	Title string `json:"title"`
+	URL   URL    `json:"url"`
+	Src   url.URL
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := New()
+	v1 := r.Group("/v1")
+	v1.GET("/users/:id", func(c *Context) {}).Summary("get a user").Tags("users").Response(http.StatusOK, apiUser{})
+	v1.POST("/users", func(c *Context) {}).Request(&apiUser{}).Response(http.StatusCreated, apiUser{}).Response(http.StatusBadRequest, H{})
+	v1.Any("/any", func(c *Context) {})
+	v1.GET("/posts/:id", func(c *Context) {}).Response(http.StatusOK, apiPost{}).Response(499, nil)
+	r.GET("/assets/*filepath", func(c *Context) {})
+	r.ServeOpenAPI("/docs", OpenAPIInfo{Title: "gee", Version: "1.0"})
+
+	w := serve(r, "GET", "/docs/openapi.json", nil)
+	var doc struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+		Comps   struct {
+			Schemas map[string]struct {
+				Required   []string               `json:"required"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" {
+		t.Fatalf("openapi = %q", doc.OpenAPI)
+	}
+	get, ok := doc.Paths["/v1/users/{id}"]["get"].(map[string]interface{})
+	if !ok || get["summary"] != "get a user" {
+		t.Fatalf("get operation = %v", doc.Paths)
+	}
+	params := get["parameters"].([]interface{})
+	if p := params[0].(map[string]interface{}); p["name"] != "id" || p["in"] != "path" {
+		t.Fatalf("parameters = %v", params)
+	}
+	if _, ok := doc.Paths["/v1/users"]["post"].(map[string]interface{})["requestBody"]; !ok {
+		t.Fatal("requestBody of POST /v1/users is missing")
+	}
+	if _, ok := doc.Paths["/assets/{filepath}"]["get"]; !ok {
+		t.Fatal("wildcard route is missing")
+	}
+	user, ok := doc.Comps.Schemas["gee.apiUser"]
+	if !ok {
+		t.Fatalf("schemas = %v", doc.Comps.Schemas)
+	}
+	if strings.Join(user.Required, ",") != "id,name,created" {
+		t.Fatalf("required = %v", user.Required)
+	}
+	if _, ok := user.Properties["secret"]; ok {
+		t.Fatal("unexported field should be skipped")
+	}
+	if created := user.Properties["created"].(map[string]interface{}); created["format"] != "date-time" {
+		t.Fatalf("created = %v", created)
+	}
+
+	if _, ok := doc.Paths["/v1/any"]["get"]; !ok {
+		t.Fatal("route of Any is missing")
+	}
+	if _, ok := doc.Paths["/v1/any"]["connect"]; ok {
+		t.Fatal("connect is not an OpenAPI operation")
+	}
+
+	responses := doc.Paths["/v1/posts/{id}"]["get"].(map[string]interface{})["responses"].(map[string]interface{})
+	if resp := responses["499"].(map[string]interface{}); resp["description"] != "Response 499" {
+		t.Fatalf("response of a custom code = %v", resp)
+	}
+
+	post, ok := doc.Comps.Schemas["gee.apiPost"]
+	if !ok {
+		t.Fatalf("schemas = %v", doc.Comps.Schemas)
+	}
+	// embedded structs are flattened like encoding/json
+	if strings.Join(post.Required, ",") != "id,title,url,Src,created" {
+		t.Fatalf("required = %v", post.Required)
+	}
+	if id := post.Properties["id"].(map[string]interface{}); id["type"] != "string" {
+		t.Fatalf("id = %v", id)
+	}
+	if _, ok := post.Properties["version"]; !ok {
+		t.Fatalf("properties = %v", post.Properties)
+	}
+	for _, name := range []string{"gee.URL", "net.url.URL"} {
+		if _, ok := doc.Comps.Schemas[name]; !ok {
+			t.Fatalf("schema %s is missing: %v", name, doc.Comps.Schemas)
+		}
+	}
+
+	w = serve(r, "GET", "/docs", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `fetch("/docs/openapi.json")`) {
+		t.Fatalf("docs page = %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,241 @@
+package gee
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balance is the load balancing strategy of Proxy
+type Balance int
+
+const (
+	RoundRobin Balance = iota // select targets in turn
+	LeastConn                 // select the target with the least active requests
+)
+
+// ProxyOptions configures Proxy
+type ProxyOptions struct {
+	Balance Balance
+	// Retries is the number of retries of idempotent requests on other targets,
+	// when the target is unreachable or returns 502, 503 or 504
+	Retries int
+	// MaxFails consecutive failures mark the target down for FailTimeout, 3 and 10s by default
+	MaxFails    int
+	FailTimeout time.Duration
+	// Transport is http.DefaultTransport by default
+	Transport http.RoundTripper
+}
+
+type proxyTarget struct {
+	url   *url.URL
+	proxy *httputil.ReverseProxy
+
+	active int64 // requests in progress
+
+	mu        sync.Mutex
+	fails     int
+	downUntil time.Time
+}
+
+func (t *proxyTarget) healthy(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return now.After(t.downUntil)
+}
+
+func (t *proxyTarget) report(ok bool, opts *ProxyOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ok {
+		t.fails = 0
+		return
+	}
+	t.fails++
+	if t.fails >= opts.MaxFails {
+		t.fails = 0
+		t.downUntil = time.Now().Add(opts.FailTimeout)
+		log.Printf("proxy: %s is marked down for %v", t.url, opts.FailTimeout)
+	}
+}
+
+var errRetryableStatus = errors.New("gee: retryable status from upstream")
+
+type proxyAttemptKey struct{}
+
+// proxyAttempt is passed to the ReverseProxy through the context of the request
+type proxyAttempt struct {
+	path     string
+	canRetry bool
+	err      error
+}
+
+type proxy struct {
+	targets []*proxyTarget
+	opts    ProxyOptions
+	next    uint64
+}
+
+// Proxy returns a handler forwarding requests to targets, e.g. `http://127.0.0.1:8001/base`.
+// On a route with a wildcard like `/api/*path`, the path is rewritten relative to it,
+// so `/api/users/1` is forwarded to `/base/users/1`.
+func Proxy(targets []string, opts ProxyOptions) HandlerFunc {
+	if len(targets) == 0 {
+		panic("gee: proxy requires at least one target")
+	}
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = 3
+	}
+	if opts.FailTimeout <= 0 {
+		opts.FailTimeout = 10 * time.Second
+	}
+	p := &proxy{opts: opts}
+	for _, target := range targets {
+		u, err := url.Parse(target)
+		if err != nil {
+			panic(err)
+		}
+		p.targets = append(p.targets, p.newTarget(u))
+	}
+	return p.handle
+}
+
+func (p *proxy) newTarget(u *url.URL) *proxyTarget {
+	t := &proxyTarget{url: u}
+	t.proxy = &httputil.ReverseProxy{
+		Transport: p.opts.Transport,
+		Director: func(req *http.Request) {
+			attempt := req.Context().Value(proxyAttemptKey{}).(*proxyAttempt)
+			req.URL.Scheme = u.Scheme
+			req.URL.Host = u.Host
+			req.URL.Path = singleJoiningSlash(u.Path, attempt.path)
+			req.URL.RawPath = ""
+			req.Host = u.Host
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			attempt := resp.Request.Context().Value(proxyAttemptKey{}).(*proxyAttempt)
+			switch resp.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				t.report(false, &p.opts)
+				if attempt.canRetry {
+					return errRetryableStatus
+				}
+			default:
+				t.report(true, &p.opts)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			attempt := req.Context().Value(proxyAttemptKey{}).(*proxyAttempt)
+			if err != errRetryableStatus && req.Context().Err() == nil {
+				t.report(false, &p.opts)
+			}
+			attempt.err = err
+		},
+	}
+	return t
+}
+
+func singleJoiningSlash(a, b string) string {
+	return strings.TrimSuffix(a, "/") + "/" + strings.TrimPrefix(b, "/")
+}
+
+// pick selects a healthy target not tried yet,
+// unhealthy targets are used if all of them are down
+func (p *proxy) pick(tried map[*proxyTarget]bool) *proxyTarget {
+	now := time.Now()
+	var candidates []*proxyTarget
+	for _, t := range p.targets {
+		if !tried[t] && t.healthy(now) {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, t := range p.targets {
+			if !tried[t] {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if p.opts.Balance == LeastConn {
+		best := candidates[0]
+		for _, t := range candidates[1:] {
+			if atomic.LoadInt64(&t.active) < atomic.LoadInt64(&best.active) {
+				best = t
+			}
+		}
+		return best
+	}
+	n := atomic.AddUint64(&p.next, 1)
+	return candidates[(n-1)%uint64(len(candidates))]
+}
+
+// relativePath returns the path relative to the route prefix
+func relativePath(c *Context) string {
+	for _, part := range parsePattern(c.FullPath()) {
+		if part[0] == '*' {
+			return "/" + c.Param(part[1:])
+		}
+	}
+	return "/"
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	case "PUT", "DELETE":
+		// the body can't be sent twice
+		return req.ContentLength == 0
+	}
+	return false
+}
+
+func (p *proxy) handle(c *Context) {
+	path := relativePath(c)
+	req := c.Req.Clone(c.Req.Context())
+	scheme := "http"
+	if c.Req.TLS != nil {
+		scheme = "https"
+	}
+	req.Header.Set("X-Forwarded-Host", c.Req.Host)
+	req.Header.Set("X-Forwarded-Proto", scheme)
+	if prefix := strings.TrimSuffix(c.Path, strings.TrimPrefix(path, "/")); prefix != "/" {
+		req.Header.Set("X-Forwarded-Prefix", strings.TrimSuffix(prefix, "/"))
+	}
+
+	retries := 0
+	if isIdempotent(req) {
+		retries = p.opts.Retries
+	}
+	tried := make(map[*proxyTarget]bool)
+	for i := 0; ; i++ {
+		t := p.pick(tried)
+		if t == nil {
+			break
+		}
+		tried[t] = true
+		attempt := &proxyAttempt{path: path, canRetry: i < retries && len(tried) < len(p.targets)}
+		atomic.AddInt64(&t.active, 1)
+		t.proxy.ServeHTTP(c.Writer, req.WithContext(context.WithValue(req.Context(), proxyAttemptKey{}, attempt)))
+		atomic.AddInt64(&t.active, -1)
+		if attempt.err == nil {
+			return
+		}
+		if !attempt.canRetry || c.Req.Context().Err() != nil {
+			break
+		}
+		log.Printf("proxy: retry %s %s, %s failed: %v", req.Method, path, t.url, attempt.err)
+	}
+	c.Fail(http.StatusBadGateway, "Bad Gateway")
+}
@@ -0,0 +1,69 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newBackend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %s", name, req.URL.Path, req.Header.Get("X-Forwarded-Host"), req.Header.Get("X-Forwarded-Prefix"))
+	}))
+}
+
+func TestProxy(t *testing.T) {
+	b1, b2 := newBackend("b1"), newBackend("b2")
+	defer b1.Close()
+	defer b2.Close()
+
+	r := New()
+	r.GET("/api/*path", Proxy([]string{b1.URL + "/base", b2.URL + "/base"}, ProxyOptions{}))
+
+	bodies := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest("GET", "/api/users/1", nil)
+		req.Host = "geektutu.com"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		bodies[w.Body.String()] = true
+	}
+	if len(bodies) != 2 || !bodies["b1 /base/users/1 geektutu.com /api"] || !bodies["b2 /base/users/1 geektutu.com /api"] {
+		t.Fatalf("requests should be balanced across targets, got %v", bodies)
+	}
+}
+
+func TestProxyRetry(t *testing.T) {
+	b1, b2 := newBackend("b1"), newBackend("b2")
+	defer b2.Close()
+	b1.Close()
+
+	r := New()
+	r.GET("/*path", Proxy([]string{b1.URL, b2.URL}, ProxyOptions{Retries: 1, MaxFails: 1}))
+	r.POST("/*path", Proxy([]string{b1.URL}, ProxyOptions{Retries: 1}))
+
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/hello", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "b2 /hello example.com " {
+			t.Fatalf("request should be retried on b2, got %d %q", w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/hello", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expect 502, got %d", w.Code)
+	}
+}
+
+func TestProxyLeastConn(t *testing.T) {
+	p := &proxy{opts: ProxyOptions{Balance: LeastConn}}
+	p.targets = []*proxyTarget{{active: 2}, {active: 1}, {active: 3}}
+	if p.pick(map[*proxyTarget]bool{}) != p.targets[1] {
+		t.Fatal("the target with the least connections should be picked")
+	}
+	if p.pick(map[*proxyTarget]bool{p.targets[1]: true}) != p.targets[0] {
+		t.Fatal("tried targets should be skipped")
+	}
+}
@@ -0,0 +1,363 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces the values of sensitive fields
+const redacted = "[REDACTED]"
+
+// DefaultRedactHeaders are the headers redacted by Recorder by default
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-CSRF-Token"}
+
+// DefaultRedactFields are the query, form and JSON fields redacted by Recorder by default
+var DefaultRedactFields = []string{"password", "token", "secret", "access_token", "refresh_token", "_csrf"}
+
+// RecorderConfig configures a Recorder, zero values are replaced by the defaults
+type RecorderConfig struct {
+	// Size is the capacity of the ring buffer, 100 by default
+	Size int
+	// SampleRate is the fraction of recorded requests in (0, 1], 1 by default
+	SampleRate float64
+	// MaxBodySize is the maximum bytes of a recorded body, 64 KB by default,
+	// bodies are still fully passed to handlers and clients
+	MaxBodySize int
+	// RedactHeaders are headers whose values are replaced, DefaultRedactHeaders by default
+	RedactHeaders []string
+	// RedactFields are query, form and JSON fields whose values are replaced,
+	// case-insensitive, DefaultRedactFields by default
+	RedactFields []string
+	// Skip is called to exclude requests, e.g. of the debug endpoint
+	Skip func(c *Context) bool
+}
+
+// Record is a recorded request and response pair
+type Record struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Route    string        `json:"route"`
+	ClientIP string        `json:"client_ip"`
+
+	Method                string      `json:"method"`
+	URL                   string      `json:"url"`
+	Proto                 string      `json:"proto"`
+	Host                  string      `json:"host"`
+	RequestHeader         http.Header `json:"request_header"`
+	RequestBody           []byte      `json:"request_body,omitempty"`
+	RequestBodyTruncated  bool        `json:"request_body_truncated,omitempty"`
+	Status                int         `json:"status"`
+	ResponseHeader        http.Header `json:"response_header"`
+	ResponseBody          []byte      `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool        `json:"response_body_truncated,omitempty"`
+}
+
+// Recorder records sampled requests and responses into a ring buffer
+type Recorder struct {
+	config        RecorderConfig
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+
+	mu      sync.Mutex
+	records []*Record
+	next    int // index of the next record in the ring buffer
+	full    bool
+}
+
+// NewRecorder creates a Recorder, its Middleware is opt-in
+func NewRecorder(config RecorderConfig) *Recorder {
+	if config.Size <= 0 {
+		config.Size = 100
+	}
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 64 << 10
+	}
+	if config.RedactHeaders == nil {
+		config.RedactHeaders = DefaultRedactHeaders
+	}
+	if config.RedactFields == nil {
+		config.RedactFields = DefaultRedactFields
+	}
+	rec := &Recorder{
+		config:        config,
+		redactHeaders: make(map[string]bool),
+		redactFields:  make(map[string]bool),
+		records:       make([]*Record, config.Size),
+	}
+	for _, header := range config.RedactHeaders {
+		rec.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, field := range config.RedactFields {
+		rec.redactFields[strings.ToLower(field)] = true
+	}
+	return rec
+}
+
+// recordWriter keeps the first max bytes of the response body
+type recordWriter struct {
+	statusWriter
+	body      bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if n := w.max - w.body.Len(); n < len(b) {
+		w.truncated = true
+		if n > 0 {
+			w.body.Write(b[:n])
+		}
+	} else {
+		w.body.Write(b)
+	}
+	return w.statusWriter.Write(b)
+}
+
+// Middleware returns the middleware recording requests
+func (rec *Recorder) Middleware() HandlerFunc {
+	return func(c *Context) {
+		if rec.config.SampleRate < 1 && rand.Float64() >= rec.config.SampleRate ||
+			rec.config.Skip != nil && rec.config.Skip(c) {
+			c.Next()
+			return
+		}
+		record := &Record{
+			Start:         time.Now(),
+			Route:         c.FullPath(),
+			ClientIP:      c.ClientIP(),
+			Method:        c.Method,
+			URL:           c.Req.URL.String(),
+			Proto:         c.Req.Proto,
+			Host:          c.Req.Host,
+			RequestHeader: c.Req.Header.Clone(),
+		}
+		if c.Req.Body != nil && c.Req.Body != http.NoBody {
+			body, _ := ioutil.ReadAll(io.LimitReader(c.Req.Body, int64(rec.config.MaxBodySize)+1))
+			if len(body) > rec.config.MaxBodySize {
+				record.RequestBodyTruncated = true
+			}
+			// the handler still reads the whole body
+			c.Req.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Req.Body), c.Req.Body}
+			if record.RequestBodyTruncated {
+				body = body[:rec.config.MaxBodySize]
+			}
+			record.RequestBody = body
+		}
+
+		w := &recordWriter{statusWriter: statusWriter{ResponseWriter: c.Writer}, max: rec.config.MaxBodySize}
+		c.Writer = w
+		defer func() {
+			// Recovery runs outside, a panic is recorded as its 500
+			err := recover()
+			c.Writer = w.ResponseWriter
+			record.Duration = time.Since(record.Start)
+			record.Status = w.status
+			if err != nil {
+				record.Status = http.StatusInternalServerError
+			} else if record.Status == 0 {
+				record.Status = http.StatusOK
+			}
+			record.ResponseHeader = w.Header().Clone()
+			record.ResponseBody = w.body.Bytes()
+			record.ResponseBodyTruncated = w.truncated
+			rec.redact(record)
+			rec.add(record)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		c.Next()
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (rec *Recorder) add(record *Record) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.records[rec.next] = record
+	rec.next = (rec.next + 1) % len(rec.records)
+	if rec.next == 0 {
+		rec.full = true
+	}
+}
+
+// Records returns the recorded requests, the oldest first
+func (rec *Recorder) Records() []*Record {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if !rec.full {
+		return append([]*Record(nil), rec.records[:rec.next]...)
+	}
+	return append(append([]*Record(nil), rec.records[rec.next:]...), rec.records[:rec.next]...)
+}
+
+// Reset drops the recorded requests
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.records = make([]*Record, len(rec.records))
+	rec.next, rec.full = 0, false
+}
+
+// redact replaces the values of sensitive headers, query, form and JSON fields
+func (rec *Recorder) redact(record *Record) {
+	for _, header := range []http.Header{record.RequestHeader, record.ResponseHeader} {
+		for key, values := range header {
+			if rec.redactHeaders[key] {
+				for i := range values {
+					values[i] = redacted
+				}
+			}
+		}
+	}
+	if u, err := url.Parse(record.URL); err == nil && u.RawQuery != "" {
+		if query, ok := rec.redactValues(u.Query()); ok {
+			u.RawQuery = query.Encode()
+			record.URL = u.String()
+		}
+	}
+	record.RequestBody = rec.redactBody(record.RequestHeader.Get("Content-Type"), record.RequestBody, record.RequestBodyTruncated)
+	record.ResponseBody = rec.redactBody(record.ResponseHeader.Get("Content-Type"), record.ResponseBody, record.ResponseBodyTruncated)
+}
+
+func (rec *Recorder) redactValues(values url.Values) (url.Values, bool) {
+	changed := false
+	for key, vs := range values {
+		if rec.redactFields[strings.ToLower(key)] {
+			for i := range vs {
+				vs[i] = redacted
+			}
+			changed = true
+		}
+	}
+	return values, changed
+}
+
+func (rec *Recorder) redactBody(contentType string, body []byte, truncated bool) []byte {
+	if len(body) == 0 || len(rec.redactFields) == 0 {
+		return body
+	}
+	switch {
+	case strings.HasPrefix(contentType, MIMEJSON):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			if rec.redactJSON(v) {
+				body, _ = json.Marshal(v)
+			}
+			return body
+		}
+	case strings.HasPrefix(contentType, MIMEPOSTForm) && !truncated:
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			if values, ok := rec.redactValues(values); ok {
+				return []byte(values.Encode())
+			}
+			return body
+		}
+	}
+	// bodies which can't be parsed are dropped if they may contain sensitive fields
+	lower := strings.ToLower(string(body))
+	for field := range rec.redactFields {
+		if strings.Contains(lower, field) {
+			return []byte(redacted)
+		}
+	}
+	return body
+}
+
+func (rec *Recorder) redactJSON(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if rec.redactFields[strings.ToLower(key)] {
+				v[key] = redacted
+				changed = true
+			} else if rec.redactJSON(value) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if rec.redactJSON(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// Handler serves the recorded requests as JSON, or as HAR with `?format=har`
+func (rec *Recorder) Handler() HandlerFunc {
+	return func(c *Context) {
+		if c.Query("format") == "har" {
+			c.SetHeader("Content-Disposition", contentDisposition("requests.har"))
+			c.JSON(http.StatusOK, rec.HAR())
+			return
+		}
+		c.JSON(http.StatusOK, rec.Records())
+	}
+}
+
+// Replay sends the record again to h and returns the response,
+// redacted values are sent as they are recorded
+func (record *Record) Replay(h http.Handler) (*http.Response, error) {
+	req, err := http.NewRequest(record.Method, record.URL, bytes.NewReader(record.RequestBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = record.RequestHeader.Clone()
+	req.Host = record.Host
+	req.RequestURI = record.URL
+	if major, minor, ok := http.ParseHTTPVersion(record.Proto); ok {
+		req.Proto, req.ProtoMajor, req.ProtoMinor = record.Proto, major, minor
+	}
+	if record.ClientIP != "" {
+		req.RemoteAddr = net.JoinHostPort(record.ClientIP, "0")
+	}
+	w := &cacheWriter{header: make(http.Header)}
+	h.ServeHTTP(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(w.status) + " " + http.StatusText(w.status),
+		StatusCode:    w.status,
+		Proto:         req.Proto,
+		ProtoMajor:    req.ProtoMajor,
+		ProtoMinor:    req.ProtoMinor,
+		Header:        w.header,
+		Body:          ioutil.NopCloser(bytes.NewReader(w.buf.Bytes())),
+		ContentLength: int64(w.buf.Len()),
+		Request:       req,
+	}, nil
+}
+
+// Replay sends the records again to h in order, it stops at the first invalid record
+func Replay(h http.Handler, records []*Record) ([]*http.Response, error) {
+	responses := make([]*http.Response, 0, len(records))
+	for _, record := range records {
+		resp, err := record.Replay(h)
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
@@ -0,0 +1,119 @@
+package gee
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(RecorderConfig{Size: 2, MaxBodySize: 32})
+	r := New()
+	r.Use(rec.Middleware())
+	r.POST("/login", func(c *Context) {
+		var body map[string]string
+		json.NewDecoder(c.Req.Body).Decode(&body)
+		c.SetHeader("Set-Cookie", "session=abc")
+		c.JSON(http.StatusOK, H{"user": body["user"], "token": "t0ps3cret"})
+	})
+	r.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, strings.Repeat("x", 100))
+	})
+
+	req := httptest.NewRequest("POST", "/login?access_token=abc&page=1", strings.NewReader(`{"user":"geektutu","password":"123456"}`))
+	req.Header.Set("Content-Type", MIMEJSON)
+	req.Header.Set("Authorization", "Bearer abc")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	// the handler still reads the whole body
+	if !strings.Contains(w.Body.String(), `"user":"geektutu"`) {
+		t.Fatalf("response = %q", w.Body.String())
+	}
+
+	records := rec.Records()
+	if len(records) != 1 {
+		t.Fatalf("%d records", len(records))
+	}
+	login := records[0]
+	if login.Route != "/login" || login.Status != 200 || !login.RequestBodyTruncated {
+		t.Fatalf("record = %+v", login)
+	}
+	// the truncated JSON body can't be parsed, it is dropped as it contains "password"
+	if string(login.RequestBody) != redacted || strings.Contains(login.URL, "access_token=abc") {
+		t.Fatalf("request is not redacted: %s %q", login.URL, login.RequestBody)
+	}
+	if login.RequestHeader.Get("Authorization") != redacted || login.ResponseHeader.Get("Set-Cookie") != redacted {
+		t.Fatalf("headers are not redacted: %v %v", login.RequestHeader, login.ResponseHeader)
+	}
+	if strings.Contains(string(login.ResponseBody), "t0ps3cret") {
+		t.Fatalf("response body is not redacted: %q", login.ResponseBody)
+	}
+
+	serve(r, "GET", "/users/1", nil)
+	serve(r, "GET", "/users/2", nil)
+	records = rec.Records()
+	if len(records) != 2 || records[0].URL != "/users/1" || records[1].URL != "/users/2" {
+		t.Fatalf("ring buffer = %v", records)
+	}
+	if len(records[1].ResponseBody) != 32 || !records[1].ResponseBodyTruncated {
+		t.Fatalf("response body = %q", records[1].ResponseBody)
+	}
+
+	responses, err := Replay(r, records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resp := range responses {
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || len(body) != 100 {
+			t.Fatalf("replay = %d %q", resp.StatusCode, body)
+		}
+	}
+}
+
+func TestRecorderPanic(t *testing.T) {
+	rec := NewRecorder(RecorderConfig{})
+	r := New()
+	r.Use(Recovery(), rec.Middleware())
+	r.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	serve(r, "GET", "/panic", nil)
+
+	records := rec.Records()
+	if len(records) != 1 || records[0].Status != http.StatusInternalServerError {
+		t.Fatalf("records = %v", records)
+	}
+}
+
+func TestRecorderHAR(t *testing.T) {
+	rec := NewRecorder(RecorderConfig{})
+	r := New()
+	r.Use(rec.Middleware())
+	r.GET("/hello", func(c *Context) { c.String(http.StatusOK, "hello %s", c.Query("name")) })
+	r.GET("/debug/requests", rec.Handler())
+	serve(r, "GET", "/hello?name=geektutu", nil)
+
+	w := serve(r, "GET", "/debug/requests?format=har", nil)
+	var har HAR
+	if err := json.Unmarshal(w.Body.Bytes(), &har); err != nil {
+		t.Fatal(err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("har = %+v", har)
+	}
+	entry := har.Log.Entries[0]
+	if entry.Request.URL != "http://example.com/hello?name=geektutu" || entry.Comment != "/hello" {
+		t.Fatalf("request = %+v", entry.Request)
+	}
+	if entry.Request.QueryString[0] != (HARPair{Name: "name", Value: "geektutu"}) {
+		t.Fatalf("query = %v", entry.Request.QueryString)
+	}
+	if entry.Response.Status != 200 || entry.Response.Content.Text != "hello geektutu" {
+		t.Fatalf("response = %+v", entry.Response)
+	}
+}
@@ -0,0 +1,404 @@
+package gee
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MIME types used by renders, Negotiate and forms
+const (
+	MIMEJSON              = "application/json"
+	MIMEHTML              = "text/html"
+	MIMEXML               = "application/xml"
+	MIMEXML2              = "text/xml"
+	MIMEPlain             = "text/plain"
+	MIMEYAML              = "application/x-yaml"
+	MIMEJS                = "application/javascript"
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// Render writes the body of a response,
+// custom formats are supported by implementing it and calling Context.Render
+type Render interface {
+	// WriteContentType sets the Content-Type header
+	WriteContentType(w http.ResponseWriter)
+	// Render writes the body
+	Render(w http.ResponseWriter) error
+}
+
+// Render writes the response by r with status code
+func (c *Context) Render(code int, r Render) {
+	r.WriteContentType(c.Writer)
+	c.Status(code)
+	if c.Method == "HEAD" || code == http.StatusNoContent || code == http.StatusNotModified {
+		return
+	}
+	if err := r.Render(c.Writer); err != nil {
+		http.Error(c.Writer, err.Error(), 500)
+	}
+}
+
+// StringRender renders text formatted by fmt.Sprintf
+type StringRender struct {
+	Format string
+	Data   []interface{}
+}
+
+func (r StringRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEPlain)
+}
+
+func (r StringRender) Render(w http.ResponseWriter) error {
+	_, err := fmt.Fprintf(w, r.Format, r.Data...)
+	return err
+}
+
+// DataRender renders raw bytes, Content-Type is only set if it is not empty
+type DataRender struct {
+	ContentType string
+	Data        []byte
+}
+
+func (r DataRender) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		w.Header().Set("Content-Type", r.ContentType)
+	}
+}
+
+func (r DataRender) Render(w http.ResponseWriter) error {
+	_, err := w.Write(r.Data)
+	return err
+}
+
+// HTMLRender renders the named template
+type HTMLRender struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+func (r HTMLRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEHTML)
+}
+
+func (r HTMLRender) Render(w http.ResponseWriter) error {
+	return r.Template.ExecuteTemplate(w, r.Name, r.Data)
+}
+
+// JSONRender renders Data as JSON
+type JSONRender struct {
+	Data interface{}
+}
+
+func (r JSONRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEJSON)
+}
+
+func (r JSONRender) Render(w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(r.Data)
+}
+
+// IndentedJSONRender renders Data as pretty JSON
+type IndentedJSONRender struct {
+	Data interface{}
+}
+
+func (r IndentedJSONRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEJSON)
+}
+
+func (r IndentedJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// SecureJSONRender renders Data as JSON prefixed by Prefix,
+// so that it can't be executed by a <script> tag of other sites
+type SecureJSONRender struct {
+	Prefix string
+	Data   interface{}
+}
+
+func (r SecureJSONRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEJSON)
+}
+
+func (r SecureJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append([]byte(r.Prefix), data...))
+	return err
+}
+
+// JSONPRender renders Data as JSON wrapped in the Callback
+type JSONPRender struct {
+	Callback string
+	Data     interface{}
+}
+
+func (r JSONPRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEJS)
+}
+
+func (r JSONPRender) Render(w http.ResponseWriter) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s(%s);", r.Callback, data)
+	return err
+}
+
+// XMLRender renders Data as XML
+type XMLRender struct {
+	Data interface{}
+}
+
+func (r XMLRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEXML)
+}
+
+func (r XMLRender) Render(w http.ResponseWriter) error {
+	if h, ok := r.Data.(H); ok {
+		return xml.NewEncoder(w).Encode(xmlMap(h))
+	}
+	return xml.NewEncoder(w).Encode(r.Data)
+}
+
+// xmlMap encodes H as `<map><key>value</key></map>`
+type xmlMap H
+
+func (m xmlMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "map"}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := e.EncodeElement(m[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// YAMLRender renders Data as YAML
+type YAMLRender struct {
+	Data interface{}
+}
+
+func (r YAMLRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEYAML+"; charset=utf-8")
+}
+
+func (r YAMLRender) Render(w http.ResponseWriter) error {
+	data, err := marshalYAML(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, IndentedJSONRender{Data: obj})
+}
+
+// SecureJSON renders JSON prefixed by Engine.SecureJSONPrefix, `while(1);` by default
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	prefix := "while(1);"
+	if c.engine != nil {
+		prefix = c.engine.SecureJSONPrefix
+	}
+	c.Render(code, SecureJSONRender{Prefix: prefix, Data: obj})
+}
+
+// JSONP renders JSON wrapped in the callback of query `callback`,
+// it renders plain JSON if the callback is missing or invalid
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if !validCallback(callback) {
+		c.JSON(code, obj)
+		return
+	}
+	c.Render(code, JSONPRender{Callback: callback, Data: obj})
+}
+
+// validCallback only allows JavaScript identifiers like `jQuery.cb_1`
+func validCallback(callback string) bool {
+	if callback == "" || len(callback) > 128 {
+		return false
+	}
+	for i, ch := range callback {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch == '_', ch == '$':
+		case (ch >= '0' && ch <= '9') || ch == '.':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, XMLRender{Data: obj})
+}
+
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, YAMLRender{Data: obj})
+}
+
+// File writes the content of the file, Range and If-Modified-Since are supported
+func (c *Context) File(filepath string) {
+	http.ServeFile(c.Writer, c.Req, filepath)
+}
+
+// FileAttachment writes the file to be downloaded as filename
+func (c *Context) FileAttachment(path, filename string) {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	c.SetHeader("Content-Disposition", contentDisposition(filename))
+	http.ServeFile(c.Writer, c.Req, path)
+}
+
+// contentDisposition returns the attachment header of RFC 6266,
+// non ASCII names are encoded by RFC 5987
+func contentDisposition(filename string) string {
+	ascii := true
+	for i := 0; i < len(filename); i++ {
+		if filename[i] < 0x20 || filename[i] >= 0x7f {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+	return `attachment; filename*=UTF-8''` + url.PathEscape(filename)
+}
+
+// Redirect replies with a redirect to location, code must be 3xx or 201
+func (c *Context) Redirect(code int, location string) {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect) && code != http.StatusCreated {
+		panic(fmt.Sprintf("gee: cannot redirect with status code %d", code))
+	}
+	c.StatusCode = code
+	http.Redirect(c.Writer, c.Req, location, code)
+}
+
+// Negotiate holds the data of each format for Context.Negotiate
+type Negotiate struct {
+	Offered  []string // MIME types offered in order of preference
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	Data     interface{} // used if the data of the format is nil
+}
+
+// Negotiate renders the format selected by the Accept header,
+// 406 Not Acceptable is returned if none of the offered formats is accepted
+func (c *Context) Negotiate(code int, config Negotiate) {
+	pick := func(data interface{}) interface{} {
+		if data == nil {
+			return config.Data
+		}
+		return data
+	}
+	switch c.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(config.JSONData))
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, pick(config.HTMLData))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(config.XMLData))
+	case MIMEYAML:
+		c.YAML(code, pick(config.YAMLData))
+	default:
+		c.Fail(http.StatusNotAcceptable, "the accepted formats are not offered by the server")
+	}
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// NegotiateFormat returns the offered MIME type best matching the Accept header,
+// the first offered is returned if there is no Accept header
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	header := c.Req.Header.Get("Accept")
+	if header == "" {
+		return offered[0]
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	best, bestQ := "", 0.0
+	for _, o := range offered {
+		// the most specific range matching o decides its quality
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := matchMediaType(r.mediaType, o); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		// ties are broken by the order of offered
+		if specificity >= 0 && q > bestQ {
+			best, bestQ = o, q
+		}
+	}
+	return best
+}
+
+// matchMediaType returns the specificity of pattern matching mediaType, -1 if not matched
+func matchMediaType(pattern, mediaType string) int {
+	switch {
+	case pattern == mediaType:
+		return 2
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, pattern[:len(pattern)-1]):
+		return 1
+	case pattern == "*/*":
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,102 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type renderStudent struct {
+	Name    string   `yaml:"name" xml:"name"`
+	Age     int      `yaml:"age" xml:"age"`
+	Tags    []string `yaml:"tags,omitempty" xml:"tag"`
+	private string
+}
+
+func serve(r *Engine, method, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRenders(t *testing.T) {
+	stu := &renderStudent{Name: "geektutu", Age: 20, Tags: []string{"go", "yes"}}
+	r := New()
+	r.GET("/xml", func(c *Context) { c.XML(http.StatusOK, stu) })
+	r.GET("/yaml", func(c *Context) { c.YAML(http.StatusOK, H{"student": stu, "list": []H{{"a": 1}}, "empty": H{}}) })
+	r.GET("/indented", func(c *Context) { c.IndentedJSON(http.StatusOK, H{"a": 1}) })
+	r.GET("/secure", func(c *Context) { c.SecureJSON(http.StatusOK, []int{1, 2}) })
+	r.GET("/jsonp", func(c *Context) { c.JSONP(http.StatusOK, H{"a": 1}) })
+	r.GET("/redirect", func(c *Context) { c.Redirect(http.StatusFound, "/xml") })
+
+	cases := []struct {
+		path, contentType, body string
+	}{
+		{"/xml", MIMEXML, "<renderStudent><name>geektutu</name><age>20</age><tag>go</tag><tag>yes</tag></renderStudent>"},
+		{"/yaml", MIMEYAML + "; charset=utf-8", "empty: {}\nlist:\n  - a: 1\nstudent:\n  name: geektutu\n  age: 20\n  tags:\n    - go\n    - \"yes\"\n"},
+		{"/indented", MIMEJSON, "{\n    \"a\": 1\n}"},
+		{"/secure", MIMEJSON, "while(1);[1,2]"},
+		{"/jsonp?callback=cb.fn", MIMEJS, `cb.fn({"a":1});`},
+		{"/jsonp?callback=alert(1)", MIMEJSON, "{\"a\":1}\n"},
+	}
+	for _, cs := range cases {
+		w := serve(r, "GET", cs.path, nil)
+		if w.Header().Get("Content-Type") != cs.contentType || w.Body.String() != cs.body {
+			t.Fatalf("%s: unexpected response %s\n%s", cs.path, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+	if w := serve(r, "GET", "/redirect", nil); w.Code != http.StatusFound || w.Header().Get("Location") != "/xml" {
+		t.Fatalf("unexpected redirect %d %v", w.Code, w.Header())
+	}
+}
+
+func TestFileAttachment(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.txt")
+	_ = os.WriteFile(file, []byte("hello gee"), 0644)
+	r := New()
+	r.GET("/file", func(c *Context) { c.File(file) })
+	r.GET("/download", func(c *Context) { c.FileAttachment(file, c.Query("name")) })
+
+	if w := serve(r, "GET", "/file", nil); w.Body.String() != "hello gee" {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+	w := serve(r, "GET", "/download?name=a+b.txt", nil)
+	if w.Header().Get("Content-Disposition") != `attachment; filename="a b.txt"` || w.Body.String() != "hello gee" {
+		t.Fatalf("unexpected response %v", w.Header())
+	}
+	w = serve(r, "GET", "/download?name=报告.txt", nil)
+	if w.Header().Get("Content-Disposition") != `attachment; filename*=UTF-8''%E6%8A%A5%E5%91%8A.txt` {
+		t.Fatalf("unexpected response %v", w.Header())
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEJSON, MIMEXML, MIMEYAML}, Data: H{"a": 1}})
+	})
+	cases := map[string]string{
+		"":                                  MIMEJSON,
+		"application/xml":                   MIMEXML,
+		"text/html, application/*;q=0.5":    MIMEJSON,
+		"application/json;q=0.5, */*;q=0.8": MIMEXML,
+		"application/x-yaml, */*;q=0.1":     MIMEYAML + "; charset=utf-8",
+	}
+	for accept, contentType := range cases {
+		w := serve(r, "GET", "/", map[string]string{"Accept": accept})
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), contentType) {
+			t.Fatalf("%s: expect %s, got %s", accept, contentType, w.Header().Get("Content-Type"))
+		}
+	}
+	if w := serve(r, "GET", "/", map[string]string{"Accept": "text/html"}); w.Code != http.StatusNotAcceptable {
+		t.Fatalf("expect 406, got %d", w.Code)
+	}
+}
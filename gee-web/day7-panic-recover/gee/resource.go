@@ -0,0 +1,120 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// controllers of Resource implement any of the following interfaces,
+// routes are registered only for the implemented actions
+type (
+	// Indexer handles `GET /users`
+	Indexer interface{ Index(c *Context) }
+	// Creator handles `POST /users`
+	Creator interface{ Create(c *Context) }
+	// Shower handles `GET /users/:id`
+	Shower interface{ Show(c *Context) }
+	// Updater handles `PUT /users/:id` and `PATCH /users/:id`
+	Updater interface{ Update(c *Context) }
+	// Destroyer handles `DELETE /users/:id`
+	Destroyer interface{ Destroy(c *Context) }
+	// NewFormer handles `GET /users/new`, the form of Create
+	NewFormer interface{ New(c *Context) }
+	// Editor handles `GET /users/:id/edit`, the form of Update
+	Editor interface{ Edit(c *Context) }
+)
+
+// Resource registers the RESTful routes of the controller under relativePath.
+// It returns the group of a single member, e.g. `/users/:user_id`,
+// to register nested resources like `/users/:user_id/posts`.
+// The param of the nested group is the singular of the last segment plus `_id`.
+func (group *RouterGroup) Resource(relativePath string, controller interface{}, middlewares ...HandlerFunc) *RouterGroup {
+	relativePath = "/" + strings.Trim(relativePath, "/")
+	member := relativePath + "/:id"
+	with := func(handler HandlerFunc) []HandlerFunc {
+		return append(append([]HandlerFunc(nil), middlewares...), handler)
+	}
+	if ctrl, ok := controller.(Indexer); ok {
+		group.GET(relativePath, with(ctrl.Index)...)
+	}
+	if ctrl, ok := controller.(NewFormer); ok {
+		group.GET(relativePath+"/new", with(ctrl.New)...)
+	}
+	if ctrl, ok := controller.(Creator); ok {
+		group.POST(relativePath, with(ctrl.Create)...)
+	}
+	if ctrl, ok := controller.(Shower); ok {
+		group.GET(member, with(ctrl.Show)...)
+	}
+	if ctrl, ok := controller.(Editor); ok {
+		group.GET(member+"/edit", with(ctrl.Edit)...)
+	}
+	if ctrl, ok := controller.(Updater); ok {
+		group.PUT(member, with(ctrl.Update)...)
+		group.PATCH(member, with(ctrl.Update)...)
+	}
+	if ctrl, ok := controller.(Destroyer); ok {
+		group.DELETE(member, with(ctrl.Destroy)...)
+	}
+	nested := group.Group(relativePath + "/:" + singular(path.Base(relativePath)) + "_id")
+	// group middlewares are matched by literal prefixes, which never match params,
+	// so the middlewares are attached to the nested routes instead
+	nested.handlers = append(append([]HandlerFunc(nil), group.handlers...), middlewares...)
+	return nested
+}
+
+// singular is a naive singular form of English nouns, e.g. users -> user, categories -> category
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// maxOverrideBody is the most bytes of a form body read by overrideMethod
+const maxOverrideBody = 4 << 10
+
+// overrideMethod replaces the method of POST requests by the `X-HTTP-Method-Override` header,
+// the `_method` query, e.g. `<form method="post" action="/users/1?_method=delete">`,
+// or the `_method` field of an urlencoded body, e.g. `<input type="hidden" name="_method" value="delete">`.
+// At most maxOverrideBody bytes of the body are read, and put back for the matched route.
+func overrideMethod(req *http.Request) {
+	if req.Method != http.MethodPost {
+		return
+	}
+	method := req.Header.Get("X-HTTP-Method-Override")
+	if method == "" {
+		method = req.URL.Query().Get("_method")
+	}
+	if method == "" && req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), MIMEPOSTForm) {
+		method = overrideBodyMethod(req)
+	}
+	switch method = strings.ToUpper(method); method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		req.Method = method
+	}
+}
+
+// overrideBodyMethod reads `_method` from the head of the urlencoded body,
+// the bytes read are put back in front of the body
+func overrideBodyMethod(req *http.Request) string {
+	head, _ := ioutil.ReadAll(io.LimitReader(req.Body, maxOverrideBody))
+	req.Body = readCloser{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+	fields := string(head)
+	if len(head) == maxOverrideBody {
+		// the last field may be cut
+		fields = fields[:strings.LastIndexByte(fields, '&')+1]
+	}
+	values, _ := url.ParseQuery(fields)
+	return values.Get("_method")
+}
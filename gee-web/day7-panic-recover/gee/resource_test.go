@@ -0,0 +1,152 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type userController struct{}
+
+func (userController) Index(c *Context)   { c.String(http.StatusOK, "index") }
+func (userController) New(c *Context)     { c.String(http.StatusOK, "new") }
+func (userController) Create(c *Context)  { c.String(http.StatusCreated, "create") }
+func (userController) Show(c *Context)    { c.String(http.StatusOK, "show "+c.Param("id")) }
+func (userController) Edit(c *Context)    { c.String(http.StatusOK, "edit "+c.Param("id")) }
+func (userController) Update(c *Context)  { c.String(http.StatusOK, "update "+c.Param("id")) }
+func (userController) Destroy(c *Context) { c.String(http.StatusOK, "destroy "+c.Param("id")) }
+
+// postController only implements reading
+type postController struct{}
+
+func (postController) Index(c *Context) { c.String(http.StatusOK, "posts of "+c.Param("user_id")) }
+func (postController) Show(c *Context) {
+	c.String(http.StatusOK, "post "+c.Param("id")+" of "+c.Param("user_id"))
+}
+
+func TestResource(t *testing.T) {
+	r := New()
+	r.MethodOverride = true
+	users := r.Group("/api").Resource("/users", userController{})
+	users.Resource("/posts", postController{})
+
+	tests := []struct {
+		method, path, body string
+		code               int
+	}{
+		{"GET", "/api/users", "index", 200},
+		{"GET", "/api/users/new", "new", 200},
+		{"POST", "/api/users", "create", 201},
+		{"GET", "/api/users/1", "show 1", 200},
+		{"GET", "/api/users/1/edit", "edit 1", 200},
+		{"PUT", "/api/users/1", "update 1", 200},
+		{"PATCH", "/api/users/1", "update 1", 200},
+		{"DELETE", "/api/users/1", "destroy 1", 200},
+		{"GET", "/api/users/1/posts", "posts of 1", 200},
+		{"GET", "/api/users/1/posts/2", "post 2 of 1", 200},
+		{"DELETE", "/api/users/1/posts/2", "", 404},
+	}
+	for _, tt := range tests {
+		w := serve(r, tt.method, tt.path, nil)
+		if w.Code != tt.code || tt.code == 200 && w.Body.String() != tt.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+
+	// HTML forms can only send GET and POST
+	for _, req := range []*http.Request{
+		httptest.NewRequest("POST", "/api/users/1?_method=delete", strings.NewReader("name=geektutu")),
+		httptest.NewRequest("POST", "/api/users/1", nil),
+	} {
+		if req.URL.RawQuery == "" {
+			req.Header.Set("X-HTTP-Method-Override", "DELETE")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != "destroy 1" {
+			t.Fatalf("method override of %s = %d %q", req.URL, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestMethodOverrideKeepsBody(t *testing.T) {
+	r := New()
+	r.MethodOverride = true
+	r.PUT("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, c.PostForm("name"))
+	})
+
+	// the body is left to the route
+	req := httptest.NewRequest("POST", "/users/1?_method=put", strings.NewReader("name=geektutu&_method=delete"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("override = %d %q", w.Code, w.Body.String())
+	}
+
+	// the _method field of the body, which is still readable by the route
+	req = httptest.NewRequest("POST", "/users/1", strings.NewReader("_method=put&name=geektutu"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("body override = %d %q", w.Code, w.Body.String())
+	}
+
+	// only the head of a large body is read, a cut field is ignored
+	body := "name=" + strings.Repeat("a", maxOverrideBody-len("name=&_method=pu")) + "&_method=put"
+	req = httptest.NewRequest("POST", "/users/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("cut override = %d", w.Code)
+	}
+
+	// unrouted bodies are never parsed
+	req = httptest.NewRequest("POST", "/unknown", strings.NewReader("_method=put"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || req.PostForm != nil {
+		t.Fatalf("unrouted = %d, form %v", w.Code, req.PostForm)
+	}
+}
+
+func TestResourceNestedMiddleware(t *testing.T) {
+	r := New()
+	var ran []string
+	mw := func(name string) HandlerFunc {
+		return func(c *Context) { ran = append(ran, name) }
+	}
+	r.Resource("/posts", postController{}, mw("posts")).Resource("/comments", postController{}, mw("comments"))
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/posts", "posts"},
+		{"/posts/1", "posts"},
+		{"/posts/1/comments", "posts,comments"},
+		{"/posts/1/comments/2", "posts,comments"},
+	}
+	for _, tt := range tests {
+		ran = nil
+		if w := serve(r, "GET", tt.path, nil); w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", tt.path, w.Code)
+		}
+		if got := strings.Join(ran, ","); got != tt.want {
+			t.Errorf("middlewares of %s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSingular(t *testing.T) {
+	for word, want := range map[string]string{"users": "user", "categories": "category", "boxes": "box", "address": "address"} {
+		if got := singular(word); got != want {
+			t.Errorf("singular(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
@@ -3,18 +3,31 @@ package gee
 import (
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// router is copy-on-write, writers build a new table and swap it atomically,
+// so that routes can be added and removed while requests are being served
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	host  string     // pattern of the virtual host, empty for the default one
+	mu    sync.Mutex // serializes writers
+	table atomic.Value
+}
+
+// routeTable is an immutable snapshot of the routes, handlers are kept in the nodes
+type routeTable struct {
+	roots map[string]*node
 }
 
 func newRouter() *router {
-	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
-	}
+	r := &router{}
+	r.table.Store(&routeTable{roots: make(map[string]*node)})
+	return r
+}
+
+func (r *router) load() *routeTable {
+	return r.table.Load().(*routeTable)
 }
 
 // Only one * is allowed
@@ -33,48 +46,111 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
-
-	key := method + "-" + pattern
-	_, ok := r.roots[method]
+// update replaces the root of method with the result of fn
+func (r *router) update(method string, fn func(root *node) *node) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.load()
+	roots := make(map[string]*node, len(old.roots)+1)
+	for m, root := range old.roots {
+		roots[m] = root
+	}
+	root, ok := roots[method]
 	if !ok {
-		r.roots[method] = &node{}
+		root = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+	roots[method] = fn(root)
+	r.table.Store(&routeTable{roots: roots})
+}
+
+func (r *router) addRoute(method string, pattern string, handlers ...HandlerFunc) {
+	r.addRouteInfo(&RouteInfo{Method: method, Pattern: pattern}, handlers)
 }
 
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
-	root, ok := r.roots[method]
+func (r *router) addRouteInfo(info *RouteInfo, handlers []HandlerFunc) {
+	r.update(info.Method, func(root *node) *node {
+		return root.insert(info, handlers)
+	})
+}
+
+// removeRoute removes the route registered by addRoute, false is returned if not found
+func (r *router) removeRoute(method string, pattern string) bool {
+	removed := false
+	r.update(method, func(root *node) *node {
+		var n *node
+		n, removed = root.remove(pattern)
+		if !removed {
+			return root
+		}
+		return n
+	})
+	return removed
+}
+
+// param is the value of a `:param` or `*wildcard` of a route in the path
+type param struct {
+	key   string
+	value string
+}
+
+// params are the params of a route in the order of its pattern
+type params []param
+
+// get returns the value of the param key, empty if not found
+func (ps params) get(key string) string {
+	for _, p := range ps {
+		if p.key == key {
+			return p.value
+		}
+	}
+	return ""
+}
+
+// getRoute returns the route matching path, its params are appended to ps[:0],
+// so that lookups don't allocate if ps has enough capacity
+func (r *router) getRoute(method string, path string, ps params) (*node, params) {
+	root, ok := r.load().roots[method]
 
 	if !ok {
 		return nil, nil
 	}
 
-	n := root.search(searchParts, 0)
+	path = cleanPath(path)
+	n := root.search(path)
 
 	if n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
-		}
-		return n, params
+		return n, appendParams(ps[:0], n.pattern, path)
 	}
 
 	return nil, nil
 }
 
+// appendParams appends the values of `:param` and `*wildcard` of pattern in path to ps
+func appendParams(ps params, pattern string, path string) params {
+	if strings.IndexAny(pattern, ":*") < 0 {
+		return ps
+	}
+	for {
+		var part, segment string
+		part, pattern = nextSegment(pattern)
+		if part == "" {
+			return ps
+		}
+		if part[0] == '*' {
+			if len(part) > 1 {
+				ps = append(ps, param{key: part[1:], value: strings.TrimLeft(path, "/")})
+			}
+			return ps
+		}
+		segment, path = nextSegment(path)
+		if part[0] == ':' {
+			ps = append(ps, param{key: part[1:], value: segment})
+		}
+	}
+}
+
 func (r *router) getRoutes(method string) []*node {
-	root, ok := r.roots[method]
+	root, ok := r.load().roots[method]
 	if !ok {
 		return nil
 	}
@@ -84,12 +160,19 @@ func (r *router) getRoutes(method string) []*node {
 }
 
 func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
+	// the params of most routes fit in buf, the map is only made for routes with params
+	var buf [8]param
+	n, ps := r.getRoute(c.Method, c.Path, buf[:0])
 
 	if n != nil {
-		key := c.Method + "-" + n.pattern
-		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[key])
+		c.pattern = n.pattern
+		if c.Params == nil && len(ps) > 0 {
+			c.Params = make(map[string]string, len(ps))
+		}
+		for _, p := range ps {
+			c.Params[p.key] = p.value
+		}
+		c.handlers = append(c.handlers, n.handlers...)
 	} else {
 		c.handlers = append(c.handlers, func(c *Context) {
 			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
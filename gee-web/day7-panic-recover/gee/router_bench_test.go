@@ -0,0 +1,129 @@
+package gee
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// legacyNode is the trie before the radix tree, kept for benchmarks
+type legacyNode struct {
+	pattern  string
+	part     string
+	children []*legacyNode
+	isWild   bool
+}
+
+func (n *legacyNode) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+
+	part := parts[height]
+	var child *legacyNode
+	for _, c := range n.children {
+		if c.part == part || c.isWild {
+			child = c
+			break
+		}
+	}
+	if child == nil {
+		child = &legacyNode{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		n.children = append(n.children, child)
+	}
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *legacyNode) search(parts []string, height int) *legacyNode {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+
+	part := parts[height]
+	children := make([]*legacyNode, 0)
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			children = append(children, child)
+		}
+	}
+
+	for _, child := range children {
+		result := child.search(parts, height+1)
+		if result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+func benchPatterns(n int) []string {
+	patterns := make([]string, 0, n)
+	for i := 0; len(patterns) < n; i++ {
+		patterns = append(patterns,
+			fmt.Sprintf("/api/v1/resource%d", i),
+			fmt.Sprintf("/api/v1/resource%d/:id", i),
+			fmt.Sprintf("/api/v1/resource%d/:id/items", i),
+			fmt.Sprintf("/static/page%d/*filepath", i),
+		)
+	}
+	return patterns
+}
+
+var benchPaths = []string{
+	"/api/v1/resource1999",
+	"/api/v1/resource1500/42",
+	"/api/v1/resource1000/42/items",
+	"/static/page1999/css/geektutu.css",
+}
+
+func BenchmarkRadixTree(b *testing.B) {
+	r := newRouter()
+	for _, pattern := range benchPatterns(8000) {
+		r.addRoute("GET", pattern, nil)
+	}
+	root := r.load().roots["GET"]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.search(benchPaths[i%len(benchPaths)])
+	}
+}
+
+func BenchmarkLegacyTrie(b *testing.B) {
+	root := &legacyNode{}
+	for _, pattern := range benchPatterns(8000) {
+		root.insert(pattern, parsePattern(pattern), 0)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		root.search(parsePattern(benchPaths[i%len(benchPaths)]), 0)
+	}
+}
+
+func BenchmarkGetRoute(b *testing.B) {
+	r := newRouter()
+	for _, pattern := range benchPatterns(8000) {
+		r.addRoute("GET", pattern, nil)
+	}
+	for _, bm := range []struct {
+		name string
+		path string
+	}{
+		{"static", "/api/v1/resource1999"},
+		{"param", "/api/v1/resource1500/42"},
+		{"wildcard", "/static/page1999/css/geektutu.css"},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ps := make(params, 0, 8)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.getRoute("GET", bm.path, ps)
+			}
+		})
+	}
+}
@@ -27,7 +27,7 @@ func TestParsePattern(t *testing.T) {
 
 func TestGetRoute(t *testing.T) {
 	r := newTestRouter()
-	n, ps := r.getRoute("GET", "/hello/geektutu")
+	n, ps := r.getRoute("GET", "/hello/geektutu", nil)
 
 	if n == nil {
 		t.Fatal("nil shouldn't be returned")
@@ -37,24 +37,24 @@ func TestGetRoute(t *testing.T) {
 		t.Fatal("should match /hello/:name")
 	}
 
-	if ps["name"] != "geektutu" {
+	if ps.get("name") != "geektutu" {
 		t.Fatal("name should be equal to 'geektutu'")
 	}
 
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps.get("name"))
 
 }
 
 func TestGetRoute2(t *testing.T) {
 	r := newTestRouter()
-	n1, ps1 := r.getRoute("GET", "/assets/file1.txt")
-	ok1 := n1.pattern == "/assets/*filepath" && ps1["filepath"] == "file1.txt"
+	n1, ps1 := r.getRoute("GET", "/assets/file1.txt", nil)
+	ok1 := n1.pattern == "/assets/*filepath" && ps1.get("filepath") == "file1.txt"
 	if !ok1 {
 		t.Fatal("pattern shoule be /assets/*filepath & filepath shoule be file1.txt")
 	}
 
-	n2, ps2 := r.getRoute("GET", "/assets/css/test.css")
-	ok2 := n2.pattern == "/assets/*filepath" && ps2["filepath"] == "css/test.css"
+	n2, ps2 := r.getRoute("GET", "/assets/css/test.css", nil)
+	ok2 := n2.pattern == "/assets/*filepath" && ps2.get("filepath") == "css/test.css"
 	if !ok2 {
 		t.Fatal("pattern shoule be /assets/*filepath & filepath shoule be css/test.css")
 	}
@@ -72,3 +72,117 @@ func TestGetRoutes(t *testing.T) {
 		t.Fatal("the number of routes shoule be 4")
 	}
 }
+
+func TestRemoveRoute(t *testing.T) {
+	r := newTestRouter()
+	if !r.removeRoute("GET", "/hello/:name") {
+		t.Fatal("route should be removed")
+	}
+	if r.removeRoute("GET", "/hello/:name") || r.removeRoute("POST", "/") {
+		t.Fatal("removing a missing route should fail")
+	}
+	if n, _ := r.getRoute("GET", "/hello/geektutu", nil); n != nil {
+		t.Fatal("removed route should not match")
+	}
+	if n, _ := r.getRoute("GET", "/hello/b/c", nil); n == nil {
+		t.Fatal("sibling route should be kept")
+	}
+	if len(r.getRoutes("GET")) != 4 {
+		t.Fatal("the number of routes should be 4")
+	}
+}
+
+func TestRouteSnapshot(t *testing.T) {
+	r := newTestRouter()
+	before := r.load()
+	r.addRoute("GET", "/new", nil)
+	if len(r.getRoutes("GET")) != 6 {
+		t.Fatal("the number of routes should be 6")
+	}
+	nodes := make([]*node, 0)
+	before.roots["GET"].travel(&nodes)
+	if len(nodes) != 5 {
+		t.Fatal("old snapshot should not be modified")
+	}
+}
+
+func TestRadixTree(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/hello/:name", nil)
+	r.addRoute("GET", "/hello/geektutu", nil)
+	r.addRoute("GET", "/help", nil)
+	r.addRoute("GET", "/users/:id/posts", nil)
+	r.addRoute("GET", "/users/:name", nil)
+	r.addRoute("GET", "/static/*filepath", nil)
+	r.addRoute("GET", "/static/js/main.js", nil)
+
+	cases := []struct {
+		path, pattern string
+		params        map[string]string
+	}{
+		{"/hello/geektutu", "/hello/geektutu", nil},
+		{"/hello/jack", "/hello/:name", map[string]string{"name": "jack"}},
+		{"/hello/jack/", "/hello/:name", map[string]string{"name": "jack"}},
+		{"//hello//jack", "/hello/:name", map[string]string{"name": "jack"}},
+		{"/help", "/help", nil},
+		{"/users/1/posts", "/users/:id/posts", map[string]string{"id": "1"}},
+		{"/users/jack", "/users/:name", map[string]string{"name": "jack"}},
+		{"/static/js/main.js", "/static/js/main.js", nil},
+		{"/static/js/app.js", "/static/*filepath", map[string]string{"filepath": "js/app.js"}},
+		{"/hel", "", nil},
+		{"/hello", "", nil},
+		{"/static", "", nil},
+		{"/users/1/comments", "", nil},
+	}
+	for _, cs := range cases {
+		n, params := r.getRoute("GET", cs.path, nil)
+		if cs.pattern == "" {
+			if n != nil {
+				t.Fatalf("%s should not match, got %s", cs.path, n.pattern)
+			}
+			continue
+		}
+		if n == nil || n.pattern != cs.pattern || len(params) != len(cs.params) {
+			t.Fatalf("%s should match %s, got %v %v", cs.path, cs.pattern, n, params)
+		}
+		for k, v := range cs.params {
+			if params.get(k) != v {
+				t.Fatalf("%s: param %s should be %s, got %s", cs.path, k, v, params.get(k))
+			}
+		}
+	}
+	if !r.removeRoute("GET", "/help") || !r.removeRoute("GET", "/static/*filepath") {
+		t.Fatal("routes should be removed")
+	}
+	if n, _ := r.getRoute("GET", "/hello/geektutu", nil); n == nil || n.pattern != "/hello/geektutu" {
+		t.Fatal("/hello/geektutu should be kept")
+	}
+	if n, _ := r.getRoute("GET", "/static/js/app.js", nil); n != nil {
+		t.Fatal("removed wildcard should not match")
+	}
+}
+
+func TestSearchAllocs(t *testing.T) {
+	r := newTestRouter()
+	root := r.load().roots["GET"]
+	allocs := testing.AllocsPerRun(100, func() {
+		root.search("/hello/geektutu")
+		root.search("/assets/css/test.css")
+	})
+	if allocs != 0 {
+		t.Fatalf("search should not allocate, got %v", allocs)
+	}
+}
+
+func TestGetRouteAllocs(t *testing.T) {
+	r := newTestRouter()
+	ps := make(params, 0, 8)
+	for _, path := range []string{"/", "/hello/b/c", "/hello/geektutu", "/assets/css/test.css"} {
+		allocs := testing.AllocsPerRun(100, func() {
+			r.getRoute("GET", path, ps)
+		})
+		if allocs != 0 {
+			t.Fatalf("getRoute(%s) should not allocate, got %v", path, allocs)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package sessions provides the session middleware of gee
+// with cookie, in-memory and file-system stores.
+package sessions
+
+import (
+	"encoding/gob"
+	"net/http"
+	"time"
+
+	"gee"
+)
+
+// DefaultKey is the key of the session in gee.Context
+const DefaultKey = "gee/sessions"
+
+const flashKey = "_flash"
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Options is the cookie and expiry options of the session
+type Options struct {
+	Path     string
+	Domain   string
+	MaxAge   int // seconds, 0 means a browser session cookie, < 0 deletes the cookie
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+	// IdleTimeout expires the session if it is not saved (cookie store)
+	// or not accessed (memory & file store) in the duration, 0 means no idle expiry
+	IdleTimeout time.Duration
+}
+
+// DefaultOptions is used by stores created without options
+var DefaultOptions = Options{
+	Path:        "/",
+	MaxAge:      86400 * 7,
+	HttpOnly:    true,
+	SameSite:    http.SameSiteLaxMode,
+	IdleTimeout: 30 * time.Minute,
+}
+
+// Store loads and saves sessions
+type Store interface {
+	// Load returns the session of the request, a new session is returned
+	// if there is none or it is invalid or expired
+	Load(c *gee.Context, name string) (*Session, error)
+	// Save writes the session to the store and the response cookie
+	Save(c *gee.Context, s *Session) error
+}
+
+// Session holds the values of a client across requests
+type Session struct {
+	ID     string // empty for cookie store
+	Values map[string]interface{}
+	IsNew  bool
+
+	name  string
+	store Store
+	c     *gee.Context
+}
+
+// NewSession creates an empty session, used by Store implementations
+func NewSession(store Store, name string) *Session {
+	return &Session{Values: make(map[string]interface{}), IsNew: true, store: store, name: name}
+}
+
+// Name returns the cookie name of the session
+func (s *Session) Name() string {
+	return s.name
+}
+
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+}
+
+func (s *Session) Delete(key string) {
+	delete(s.Values, key)
+}
+
+// Clear deletes all values of the session
+func (s *Session) Clear() {
+	for key := range s.Values {
+		delete(s.Values, key)
+	}
+}
+
+// AddFlash adds a flash message, which is deleted once read by Flashes
+func (s *Session) AddFlash(value interface{}) {
+	flashes, _ := s.Values[flashKey].([]interface{})
+	s.Values[flashKey] = append(flashes, value)
+}
+
+// Flashes returns and deletes the flash messages,
+// call Save to persist the deletion
+func (s *Session) Flashes() []interface{} {
+	flashes, _ := s.Values[flashKey].([]interface{})
+	delete(s.Values, flashKey)
+	return flashes
+}
+
+// Save saves the session, it must be called before writing the response body
+func (s *Session) Save() error {
+	return s.store.Save(s.c, s)
+}
+
+// Sessions is the middleware loading the session named name from store,
+// the session is available through Default
+func Sessions(name string, store Store) gee.HandlerFunc {
+	return func(c *gee.Context) {
+		s, err := store.Load(c, name)
+		if err != nil {
+			// invalid or unreadable session, start a new one
+			s = NewSession(store, name)
+		}
+		s.store, s.name, s.c = store, name, c
+		c.Set(DefaultKey, s)
+		c.Next()
+	}
+}
+
+// Default returns the session loaded by Sessions
+func Default(c *gee.Context) *Session {
+	return c.MustGet(DefaultKey).(*Session)
+}
+
+const csrfKey = "_csrf"
+
+type csrfStore struct{}
+
+// CSRFStore returns a gee.CSRFStore keeping the CSRF token in the session loaded by Sessions
+func CSRFStore() gee.CSRFStore {
+	return csrfStore{}
+}
+
+func (csrfStore) GetToken(c *gee.Context) string {
+	token, _ := Default(c).Get(csrfKey).(string)
+	return token
+}
+
+func (csrfStore) SaveToken(c *gee.Context, token string) error {
+	session := Default(c)
+	session.Set(csrfKey, token)
+	return session.Save()
+}
@@ -0,0 +1,165 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func newTestEngine(store Store) *gee.Engine {
+	r := gee.New()
+	r.Use(Sessions("geesession", store))
+	r.GET("/login", func(c *gee.Context) {
+		session := Default(c)
+		session.Set("user", "geektutu")
+		session.AddFlash("welcome")
+		_ = session.Save()
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/whoami", func(c *gee.Context) {
+		session := Default(c)
+		flashes := session.Flashes()
+		_ = session.Save()
+		user, _ := session.Get("user").(string)
+		c.String(http.StatusOK, "%s %v", user, flashes)
+	})
+	return r
+}
+
+func do(r *gee.Engine, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func testStore(t *testing.T, store Store) {
+	r := newTestEngine(store)
+	cookies := do(r, "/login", nil).Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatal("session cookie should be set")
+	}
+	w := do(r, "/whoami", cookies)
+	if body := w.Body.String(); body != "geektutu [welcome]" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	// flashes are read only once
+	if body := do(r, "/whoami", w.Result().Cookies()).Body.String(); body != "geektutu []" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if body := do(r, "/whoami", nil).Body.String(); body != " []" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	testStore(t, NewCookieStore([]byte("secret")))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore([]byte("secret")))
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, NewFileStore(t.TempDir(), []byte("secret")))
+}
+
+func TestKeyRotation(t *testing.T) {
+	cookies := do(newTestEngine(NewCookieStore([]byte("old"))), "/login", nil).Result().Cookies()
+
+	r := newTestEngine(NewCookieStore([]byte("new"), []byte("old")))
+	if body := do(r, "/whoami", cookies).Body.String(); body != "geektutu [welcome]" {
+		t.Fatalf("old key should be accepted, got %q", body)
+	}
+	r = newTestEngine(NewCookieStore([]byte("new")))
+	if body := do(r, "/whoami", cookies).Body.String(); body != " []" {
+		t.Fatalf("removed key should be rejected, got %q", body)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	store := NewMemoryStore([]byte("secret"))
+	store.Options.IdleTimeout = 20 * time.Millisecond
+	r := newTestEngine(store)
+	cookies := do(r, "/login", nil).Result().Cookies()
+	time.Sleep(40 * time.Millisecond)
+	if body := do(r, "/whoami", cookies).Body.String(); body != " []" {
+		t.Fatalf("session should be expired, got %q", body)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore([]byte("secret"))
+	store.Options.IdleTimeout = 20 * time.Millisecond
+	r := newTestEngine(store)
+	for i := 0; i < 10; i++ {
+		do(r, "/login", nil) // abandoned
+	}
+	time.Sleep(40 * time.Millisecond)
+	cookies := do(r, "/login", nil).Result().Cookies()
+
+	m := store.backend.(*memoryBackend)
+	m.mu.Lock()
+	n := len(m.items)
+	m.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("%d sessions are kept, want 1", n)
+	}
+	if body := do(r, "/whoami", cookies).Body.String(); body != "geektutu [welcome]" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestFileStoreSweep(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, []byte("secret"))
+	store.Options.IdleTimeout = 20 * time.Millisecond
+	r := newTestEngine(store)
+	for i := 0; i < 10; i++ {
+		do(r, "/login", nil) // abandoned
+	}
+	time.Sleep(40 * time.Millisecond)
+	cookies := do(r, "/login", nil).Result().Cookies()
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "session_*"))
+	if len(paths) != 1 {
+		t.Fatalf("%d sessions are kept, want 1", len(paths))
+	}
+	if body := do(r, "/whoami", cookies).Body.String(); body != "geektutu [welcome]" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestCSRFStore(t *testing.T) {
+	r := gee.New()
+	r.Use(Sessions("geesession", NewMemoryStore([]byte("secret"))), gee.CSRF(gee.CSRFConfig{Store: CSRFStore()}))
+	r.GET("/token", func(c *gee.Context) {
+		c.String(http.StatusOK, c.MustGet(gee.CSRFKey).(string))
+	})
+	r.POST("/submit", func(c *gee.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := do(r, "/token", nil)
+	token, cookies := w.Body.String(), w.Result().Cookies()
+	for _, cs := range []struct {
+		token string
+		code  int
+	}{{token, http.StatusOK}, {"forged", http.StatusForbidden}} {
+		req := httptest.NewRequest("POST", "/submit", nil)
+		req.Header.Set("X-CSRF-Token", cs.token)
+		req.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != cs.code {
+			t.Fatalf("expect %d, got %d", cs.code, w.Code)
+		}
+	}
+}
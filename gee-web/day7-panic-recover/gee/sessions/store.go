@@ -0,0 +1,333 @@
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gee"
+)
+
+// ErrExpired is returned when loading an idle expired session
+var ErrExpired = errors.New("sessions: session expired")
+
+// record is the encoded form of a session
+type record struct {
+	Values  map[string]interface{}
+	Touched time.Time
+}
+
+func encode(values map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&record{Values: values, Touched: time.Now()})
+	return buf.Bytes(), err
+}
+
+func decode(data []byte) (*record, error) {
+	var r record
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r)
+	if r.Values == nil {
+		r.Values = make(map[string]interface{})
+	}
+	return &r, err
+}
+
+func expired(opts *Options, touched time.Time) bool {
+	return opts.IdleTimeout > 0 && time.Since(touched) > opts.IdleTimeout
+}
+
+func setCookie(c *gee.Context, opts *Options, name, value string) {
+	c.SetSameSite(opts.SameSite)
+	c.SetCookie(name, value, opts.MaxAge, opts.Path, opts.Domain, opts.Secure, opts.HttpOnly)
+}
+
+// CookieStore keeps the whole session in an AES-GCM encrypted cookie
+type CookieStore struct {
+	Options Options
+	codec   *gee.SecureCookie
+}
+
+// NewCookieStore creates a CookieStore, the first key encrypts sessions,
+// all of them decrypt, so that keys can be rotated
+func NewCookieStore(keys ...[]byte) *CookieStore {
+	return &CookieStore{Options: DefaultOptions, codec: gee.NewSecureCookie(keys...)}
+}
+
+func (s *CookieStore) Load(c *gee.Context, name string) (*Session, error) {
+	session := NewSession(s, name)
+	value, err := c.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	plain, err := s.codec.Decrypt(name, value)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decode([]byte(plain))
+	if err != nil {
+		return nil, err
+	}
+	if expired(&s.Options, r.Touched) {
+		return nil, ErrExpired
+	}
+	session.Values, session.IsNew = r.Values, false
+	return session, nil
+}
+
+func (s *CookieStore) Save(c *gee.Context, session *Session) error {
+	if s.Options.MaxAge < 0 {
+		setCookie(c, &s.Options, session.name, "")
+		return nil
+	}
+	data, err := encode(session.Values)
+	if err != nil {
+		return err
+	}
+	value, err := s.codec.Encrypt(session.name, string(data))
+	if err != nil {
+		return err
+	}
+	setCookie(c, &s.Options, session.name, value)
+	return nil
+}
+
+// backend stores encoded sessions by id
+type backend interface {
+	read(id string) (data []byte, touched time.Time, err error)
+	write(id string, data []byte) error
+	touch(id string) error
+	remove(id string) error
+}
+
+// sweeper is a backend which drops sessions idle longer than timeout,
+// it is called on writes so that abandoned sessions don't pile up
+type sweeper interface {
+	sweep(timeout time.Duration)
+}
+
+// idStore keeps the session in a backend, the cookie only holds the signed session id
+type idStore struct {
+	Options Options
+	codec   *gee.SecureCookie
+	backend backend
+}
+
+func newID() (string, error) {
+	b := make([]byte, 24)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *idStore) Load(c *gee.Context, name string) (*Session, error) {
+	session := NewSession(s, name)
+	value, err := c.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	id, err := s.codec.Verify(name, value)
+	if err != nil {
+		return nil, err
+	}
+	data, touched, err := s.backend.read(id)
+	if err != nil {
+		// unknown id, e.g. removed or the server restarted
+		return session, nil
+	}
+	if expired(&s.Options, touched) {
+		_ = s.backend.remove(id)
+		return nil, ErrExpired
+	}
+	r, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.backend.touch(id)
+	session.ID, session.Values, session.IsNew = id, r.Values, false
+	return session, nil
+}
+
+func (s *idStore) Save(c *gee.Context, session *Session) error {
+	if s.Options.MaxAge < 0 {
+		if session.ID != "" {
+			_ = s.backend.remove(session.ID)
+		}
+		setCookie(c, &s.Options, session.name, "")
+		return nil
+	}
+	if session.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+	data, err := encode(session.Values)
+	if err != nil {
+		return err
+	}
+	if err = s.backend.write(session.ID, data); err != nil {
+		return err
+	}
+	if sw, ok := s.backend.(sweeper); ok {
+		if timeout := s.timeout(); timeout > 0 {
+			sw.sweep(timeout)
+		}
+	}
+	setCookie(c, &s.Options, session.name, s.codec.Sign(session.name, session.ID))
+	return nil
+}
+
+// timeout is the idle time after which a session is never loaded again,
+// the cookie of a session expires MaxAge after its last save
+func (s *idStore) timeout() time.Duration {
+	if s.Options.IdleTimeout > 0 {
+		return s.Options.IdleTimeout
+	}
+	return time.Duration(s.Options.MaxAge) * time.Second
+}
+
+// MemoryStore keeps sessions in memory, the cookie holds the signed session id
+type MemoryStore struct {
+	*idStore
+}
+
+// NewMemoryStore creates a MemoryStore, keys sign the session id like NewCookieStore
+func NewMemoryStore(keys ...[]byte) *MemoryStore {
+	m := &memoryBackend{items: make(map[string]*memoryItem)}
+	return &MemoryStore{&idStore{Options: DefaultOptions, codec: gee.NewSecureCookie(keys...), backend: m}}
+}
+
+type memoryItem struct {
+	data    []byte
+	touched time.Time
+}
+
+type memoryBackend struct {
+	mu    sync.Mutex
+	items map[string]*memoryItem
+	swept time.Time // last sweep, sweeps run at most once per timeout
+}
+
+func (m *memoryBackend) read(id string) ([]byte, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[id]
+	if !ok {
+		return nil, time.Time{}, os.ErrNotExist
+	}
+	return item.data, item.touched, nil
+}
+
+func (m *memoryBackend) write(id string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[id] = &memoryItem{data: data, touched: time.Now()}
+	return nil
+}
+
+func (m *memoryBackend) touch(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if item, ok := m.items[id]; ok {
+		item.touched = time.Now()
+	}
+	return nil
+}
+
+func (m *memoryBackend) remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, id)
+	return nil
+}
+
+func (m *memoryBackend) sweep(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.swept) < timeout {
+		return
+	}
+	m.swept = now
+	for id, item := range m.items {
+		if now.Sub(item.touched) > timeout {
+			delete(m.items, id)
+		}
+	}
+}
+
+// FileStore keeps sessions as files under a directory, the cookie holds the signed session id
+type FileStore struct {
+	*idStore
+}
+
+// NewFileStore creates a FileStore in dir, keys sign the session id like NewCookieStore
+func NewFileStore(dir string, keys ...[]byte) *FileStore {
+	return &FileStore{&idStore{Options: DefaultOptions, codec: gee.NewSecureCookie(keys...), backend: &fileBackend{dir: dir}}}
+}
+
+type fileBackend struct {
+	dir   string
+	mu    sync.Mutex
+	swept time.Time // last sweep, sweeps run at most once per timeout
+}
+
+func (f *fileBackend) path(id string) string {
+	// ids are verified before, just in case
+	return filepath.Join(f.dir, "session_"+strings.Replace(id, string(filepath.Separator), "", -1))
+}
+
+func (f *fileBackend) read(id string) ([]byte, time.Time, error) {
+	info, err := os.Stat(f.path(id))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := ioutil.ReadFile(f.path(id))
+	return data, info.ModTime(), err
+}
+
+func (f *fileBackend) write(id string, data []byte) error {
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.path(id), data, 0600)
+}
+
+func (f *fileBackend) touch(id string) error {
+	now := time.Now()
+	return os.Chtimes(f.path(id), now, now)
+}
+
+func (f *fileBackend) remove(id string) error {
+	return os.Remove(f.path(id))
+}
+
+func (f *fileBackend) sweep(timeout time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if now.Sub(f.swept) < timeout {
+		return
+	}
+	f.swept = now
+	paths, err := filepath.Glob(filepath.Join(f.dir, "session_*"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) > timeout {
+			os.Remove(path)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gee
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutWriter buffers the response, which is written only if the handler finishes in time
+type timeoutWriter struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() || tw.status != 0 {
+		return
+	}
+	tw.status = code
+}
+
+// expired marks the writer timed out once the deadline is exceeded, tw.mu must be held
+func (tw *timeoutWriter) expired() bool {
+	if !tw.timedOut && tw.ctx.Err() != nil {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+// Timeout returns a middleware which gives up the request after d,
+// 503 Service Unavailable is returned and late writes of the handler are dropped
+func Timeout(d time.Duration) HandlerFunc {
+	return TimeoutWithHandler(d, func(c *Context) {
+		c.String(http.StatusServiceUnavailable, "Service Unavailable")
+	})
+}
+
+// TimeoutWithHandler is like Timeout, handler writes the response of timed out requests
+func TimeoutWithHandler(d time.Duration, handler HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), d)
+		defer cancel()
+
+		// the rest handlers run on a copy of the context in another goroutine,
+		// so that the timed out handler never touches c
+		tw := &timeoutWriter{ctx: ctx, header: make(http.Header)}
+		cc := *c
+		cc.Writer = tw
+		cc.Req = c.Req.WithContext(ctx)
+		cc.Keys = make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			cc.Keys[k] = v
+		}
+
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					panicChan <- err
+					return
+				}
+				close(done)
+			}()
+			cc.Next()
+		}()
+
+		select {
+		case err := <-panicChan:
+			panic(err)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			// the handler wrote after the deadline
+			if tw.timedOut {
+				break
+			}
+			dst := c.Writer.Header()
+			for k, v := range tw.header {
+				dst[k] = v
+			}
+			if tw.status == 0 {
+				tw.status = http.StatusOK
+			}
+			c.Writer.WriteHeader(tw.status)
+			c.Writer.Write(tw.buf.Bytes())
+			c.StatusCode, c.Keys, c.index = cc.StatusCode, cc.Keys, cc.index
+			return
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			go func() {
+				// the abandoned handler may still panic
+				select {
+				case err := <-panicChan:
+					log.Printf("panic after timeout: %v", err)
+				case <-done:
+				}
+			}()
+		}
+		c.index = len(c.handlers)
+		handler(c)
+	}
+}
@@ -0,0 +1,60 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContextValue(t *testing.T) {
+	type ctxKey struct{}
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "request"))
+	c := newContext(httptest.NewRecorder(), req)
+	c.Set("user", "geektutu")
+
+	var ctx context.Context = c
+	if ctx.Value("user") != "geektutu" || ctx.Value(ctxKey{}) != "request" {
+		t.Fatal("failed to get value from context")
+	}
+	if _, ok := ctx.Deadline(); ok || ctx.Err() != nil {
+		t.Fatal("context should have no deadline")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	r := New()
+	late := make(chan error, 1)
+	r.GET("/slow", Timeout(20*time.Millisecond), func(c *Context) {
+		select {
+		case <-c.Done():
+		case <-time.After(time.Second):
+		}
+		_, err := c.Writer.Write([]byte("late"))
+		late <- err
+	})
+	r.GET("/fast", Timeout(time.Second), func(c *Context) {
+		if _, ok := c.Deadline(); !ok {
+			t.Error("deadline should be set")
+		}
+		c.SetHeader("X-Gee", "fast")
+		c.String(http.StatusCreated, "fast")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "Service Unavailable" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if err := <-late; err != http.ErrHandlerTimeout {
+		t.Fatalf("late write should fail, got %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Gee") != "fast" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,310 @@
+package gee
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceSpanKey is the key of the server span in Context
+const TraceSpanKey = "trace_span"
+
+// W3C trace context headers, see https://www.w3.org/TR/trace-context/
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// TraceID and SpanID are the ids of the trace context
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+func (id SpanID) IsValid() bool   { return id != SpanID{} }
+
+func (id TraceID) MarshalText() ([]byte, error) { return []byte(id.String()), nil }
+func (id SpanID) MarshalText() ([]byte, error)  { return []byte(id.String()), nil }
+
+// SpanStatus is the status of a span
+type SpanStatus string
+
+const (
+	StatusUnset SpanStatus = "unset"
+	StatusOK    SpanStatus = "ok"
+	StatusError SpanStatus = "error"
+)
+
+// Span is the server span of a request
+type Span struct {
+	TraceID    TraceID           `json:"trace_id"`
+	SpanID     SpanID            `json:"span_id"`
+	ParentID   SpanID            `json:"parent_id"`
+	TraceState string            `json:"trace_state,omitempty"`
+	Sampled    bool              `json:"sampled"`
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Status     SpanStatus        `json:"status"`
+	Error      string            `json:"error,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	mu sync.Mutex
+}
+
+// SetAttribute sets an attribute of the span, it is safe for concurrent use
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]string)
+	}
+	s.Attributes[key] = value
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Status = StatusError
+	s.Error = err.Error()
+}
+
+// Traceparent returns the `traceparent` header value of the span
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID.String() + "-" + s.SpanID.String() + "-" + flags
+}
+
+// Inject sets `traceparent` and `tracestate` of outgoing requests,
+// so that the span becomes the parent of spans of downstream services
+func (s *Span) Inject(header http.Header) {
+	header.Set(TraceparentHeader, s.Traceparent())
+	if s.TraceState != "" {
+		header.Set(TracestateHeader, s.TraceState)
+	} else {
+		header.Del(TracestateHeader)
+	}
+}
+
+// Span returns the server span of the request, it is nil without Tracing
+func (c *Context) Span() *Span {
+	if span, ok := c.Keys[TraceSpanKey].(*Span); ok {
+		return span
+	}
+	return nil
+}
+
+type spanContextKey struct{}
+
+// SpanFromContext returns the span of a *Context or c.Req.Context(),
+// e.g. the ctx passed to outgoing calls
+func SpanFromContext(ctx context.Context) *Span {
+	if span, ok := ctx.Value(TraceSpanKey).(*Span); ok {
+		return span
+	}
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// parseTraceparent parses `version-traceid-parentid-flags`,
+// versions higher than 00 may carry more fields which are ignored
+func parseTraceparent(value string) (traceID TraceID, parentID SpanID, flags byte, ok bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return
+	}
+	version, err := strconv.ParseUint(value[:2], 16, 8)
+	if err != nil || version == 0xff || !isLowerHex(value[:2]) {
+		return
+	}
+	if version == 0 && len(value) != 55 || len(value) > 55 && value[55] != '-' {
+		return
+	}
+	if !isLowerHex(value[3:35]) || !isLowerHex(value[36:52]) || !isLowerHex(value[53:55]) {
+		return
+	}
+	hex.Decode(traceID[:], []byte(value[3:35]))
+	hex.Decode(parentID[:], []byte(value[36:52]))
+	f, _ := strconv.ParseUint(value[53:55], 16, 8)
+	if !traceID.IsValid() || !parentID.IsValid() {
+		return
+	}
+	return traceID, parentID, byte(f), true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTracestate keeps at most 32 well-formed `key=value` members
+func parseTracestate(values []string) string {
+	var members []string
+	for _, value := range values {
+		for _, member := range strings.Split(value, ",") {
+			member = strings.TrimSpace(member)
+			if member == "" {
+				continue
+			}
+			if i := strings.IndexByte(member, '='); i <= 0 || i == len(member)-1 {
+				return ""
+			}
+			members = append(members, member)
+		}
+	}
+	if len(members) > 32 {
+		members = members[:32]
+	}
+	return strings.Join(members, ",")
+}
+
+func randomID(b []byte) {
+	for {
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		for _, v := range b {
+			if v != 0 {
+				return
+			}
+		}
+	}
+}
+
+// SpanExporter receives the finished spans
+type SpanExporter interface {
+	ExportSpan(span *Span)
+}
+
+// JSONExporter writes spans as JSON lines
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONExporter creates a JSONExporter writing to w, e.g. os.Stdout
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+func (e *JSONExporter) ExportSpan(span *Span) {
+	data, err := json.Marshal(span)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.w.Write(append(data, '\n'))
+}
+
+// InMemoryExporter keeps the spans in memory, it is useful for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *InMemoryExporter) ExportSpan(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans in order
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+// Reset drops the exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Tracing creates a server span per request named after the route pattern.
+// The incoming `traceparent` makes it a child of the caller's span,
+// otherwise a new sampled trace is started. The span is exported when the request
+// is done, unless the caller has not sampled it.
+func Tracing(exporter SpanExporter) HandlerFunc {
+	return func(c *Context) {
+		span := &Span{Kind: "server", Status: StatusUnset, Start: time.Now()}
+		if traceID, parentID, flags, ok := parseTraceparent(c.Req.Header.Get(TraceparentHeader)); ok {
+			span.TraceID, span.ParentID, span.Sampled = traceID, parentID, flags&1 == 1
+			span.TraceState = parseTracestate(c.Req.Header[textproto.CanonicalMIMEHeaderKey(TracestateHeader)])
+		} else {
+			randomID(span.TraceID[:])
+			span.Sampled = true
+		}
+		randomID(span.SpanID[:])
+		span.Name = c.Method
+		if route := c.FullPath(); route != "" {
+			span.Name += " " + route
+			span.SetAttribute("http.route", route)
+		}
+		span.SetAttribute("http.method", c.Method)
+		span.SetAttribute("http.target", c.Req.URL.RequestURI())
+
+		c.Set(TraceSpanKey, span)
+		c.Req = c.Req.WithContext(context.WithValue(c.Req.Context(), spanContextKey{}, span))
+		c.SetHeader(TraceparentHeader, span.Traceparent())
+
+		w := &statusWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			err := recover()
+			status := w.status
+			if err != nil {
+				span.RecordError(fmt.Errorf("panic: %v", err))
+				status = http.StatusInternalServerError
+			} else if status == 0 {
+				status = http.StatusOK
+			}
+			c.Writer = w.ResponseWriter
+			span.SetAttribute("http.status_code", strconv.Itoa(status))
+			span.mu.Lock()
+			if status >= 500 {
+				span.Status = StatusError
+				if span.Error == "" {
+					span.Error = http.StatusText(status)
+				}
+			} else if span.Status == StatusUnset {
+				span.Status = StatusOK
+			}
+			span.End = time.Now()
+			span.mu.Unlock()
+			if span.Sampled {
+				exporter.ExportSpan(span)
+			}
+			if err != nil {
+				panic(err)
+			}
+		}()
+		c.Next()
+	}
+}
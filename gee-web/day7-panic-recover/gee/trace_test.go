@@ -0,0 +1,101 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false},
+	}
+	for _, tt := range tests {
+		if _, _, _, ok := parseTraceparent(tt.value); ok != tt.ok {
+			t.Errorf("parseTraceparent(%q) = %v, want %v", tt.value, ok, tt.ok)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter := &InMemoryExporter{}
+	r := New()
+	r.Use(Tracing(exporter))
+	var outgoing http.Header
+	r.GET("/users/:id", func(c *Context) {
+		outgoing = http.Header{}
+		SpanFromContext(c.Req.Context()).Inject(outgoing)
+		c.String(http.StatusOK, c.Param("id"))
+	})
+	r.GET("/fail", func(c *Context) {
+		c.Span().RecordError(errors.New("db is down"))
+		c.Fail(http.StatusServiceUnavailable, "unavailable")
+	})
+
+	w := serve(r, "GET", "/users/1", map[string]string{
+		TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		TracestateHeader:  "congo=t61rcWkgMzE",
+	})
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans exported", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /users/:id" || span.Status != StatusOK || span.Attributes["http.status_code"] != "200" {
+		t.Fatalf("span = %+v", span)
+	}
+	if span.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ParentID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("span is not a child of the caller: %s %s", span.TraceID, span.ParentID)
+	}
+	if outgoing.Get(TraceparentHeader) != span.Traceparent() || outgoing.Get(TracestateHeader) != "congo=t61rcWkgMzE" {
+		t.Fatalf("outgoing = %v", outgoing)
+	}
+	if w.Header().Get(TraceparentHeader) != span.Traceparent() {
+		t.Fatalf("traceparent = %q", w.Header().Get(TraceparentHeader))
+	}
+
+	exporter.Reset()
+	serve(r, "GET", "/fail", nil)
+	span = exporter.Spans()[0]
+	if span.Status != StatusError || span.Error != "db is down" || span.ParentID.IsValid() {
+		t.Fatalf("span = %+v", span)
+	}
+
+	// unsampled traces are propagated but not exported
+	exporter.Reset()
+	serve(r, "GET", "/users/2", map[string]string{
+		TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+	})
+	if len(exporter.Spans()) != 0 || !strings.HasSuffix(outgoing.Get(TraceparentHeader), "-00") {
+		t.Fatalf("unsampled span exported, outgoing = %v", outgoing)
+	}
+}
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(Tracing(NewJSONExporter(&buf)))
+	r.GET("/", func(c *Context) {})
+	serve(r, "GET", "/", nil)
+
+	var span map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &span); err != nil {
+		t.Fatal(err)
+	}
+	if span["name"] != "GET /" || len(span["trace_id"].(string)) != 32 {
+		t.Fatalf("span = %v", span)
+	}
+}
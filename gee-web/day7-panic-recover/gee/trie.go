@@ -5,50 +5,223 @@ import (
 	"strings"
 )
 
+// node is a node of the radix tree. Static text is compressed into part,
+// static children are indexed by their first byte, a `:param` child matches
+// one segment and a `*wildcard` child matches the rest of the path.
+//
+// node is never modified once it is published,
+// insert and remove copy the nodes on the path and return a new root
 type node struct {
-	pattern  string
-	part     string
-	children []*node
+	pattern  string // the registered pattern of a route, empty if not a route
+	part     string // static text, or the first `:param`/`*wildcard` registered
 	isWild   bool
+	handlers []HandlerFunc
+	info     *RouteInfo
+
+	indices  string  // first bytes of children
+	children []*node // static children
+	param    *node
+	wildcard *node
 }
 
 func (n *node) String() string {
 	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.isWild)
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// token is a static text or a `:param`/`*wildcard` segment of a pattern
+type token struct {
+	text   string
+	isWild bool
+}
+
+// tokenize splits the pattern into tokens, e.g.
+// `/hello/:name/*filepath` into `/hello/`, `:name`, `/`, `*filepath`
+func tokenize(pattern string) []token {
+	var tokens []token
+	static := ""
+	for _, part := range parsePattern(pattern) {
+		static += "/"
+		if part[0] == ':' || part[0] == '*' {
+			tokens = append(tokens, token{text: static}, token{text: part, isWild: true})
+			static = ""
+			continue
+		}
+		static += part
 	}
+	if static == "" && len(tokens) == 0 {
+		static = "/"
+	}
+	if static != "" {
+		tokens = append(tokens, token{text: static})
+	}
+	return tokens
+}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
-	child.insert(pattern, parts, height+1)
+	return i
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
-			return nil
+func (n *node) insert(info *RouteInfo, handlers []HandlerFunc) *node {
+	return n.insertTokens(tokenize(info.Pattern), info, handlers)
+}
+
+func (n *node) insertTokens(tokens []token, info *RouteInfo, handlers []HandlerFunc) *node {
+	nn := *n
+	if len(tokens) == 0 {
+		nn.pattern = info.Pattern
+		nn.handlers = handlers
+		nn.info = info
+		return &nn
+	}
+	t := tokens[0]
+	if !t.isWild {
+		return n.insertStatic(t.text, func(end *node) *node {
+			return end.insertTokens(tokens[1:], info, handlers)
+		})
+	}
+	child := &node{part: t.text, isWild: true}
+	if t.text[0] == ':' {
+		if n.param != nil {
+			child = n.param
 		}
-		return n
+		nn.param = child.insertTokens(tokens[1:], info, handlers)
+	} else {
+		if n.wildcard != nil {
+			child = n.wildcard
+		}
+		// nothing is allowed after *
+		nn.wildcard = child.insertTokens(nil, info, handlers)
 	}
+	return &nn
+}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+// insertStatic consumes the static text s from the children of n,
+// and replaces the node where s ends by next(node)
+func (n *node) insertStatic(s string, next func(end *node) *node) *node {
+	if s == "" {
+		return next(n)
+	}
+	nn := *n
+	nn.children = append([]*node(nil), n.children...)
+	i := strings.IndexByte(n.indices, s[0])
+	if i < 0 {
+		nn.indices += s[:1]
+		nn.children = append(nn.children, next(&node{part: s}))
+		return &nn
+	}
+
+	child := n.children[i]
+	common := longestCommonPrefix(s, child.part)
+	if common < len(child.part) {
+		// split the child at the common prefix
+		rest := *child
+		rest.part = child.part[common:]
+		child = &node{part: child.part[:common], indices: rest.part[:1], children: []*node{&rest}}
+	}
+	nn.children[i] = child.insertStatic(s[common:], next)
+	return &nn
+}
 
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
-			return result
+// remove returns the new node without the route, nil if the node becomes empty
+func (n *node) remove(pattern string) (*node, bool) {
+	return n.removeTokens(tokenize(pattern), pattern, true)
+}
+
+func (n *node) isEmpty() bool {
+	return n.pattern == "" && len(n.children) == 0 && n.param == nil && n.wildcard == nil
+}
+
+func (n *node) removeTokens(tokens []token, pattern string, isRoot bool) (*node, bool) {
+	nn := *n
+	if len(tokens) == 0 {
+		if n.pattern != pattern {
+			return n, false
+		}
+		nn.pattern, nn.handlers, nn.info = "", nil, nil
+	} else if t := tokens[0]; t.isWild {
+		child := n.param
+		if t.text[0] == '*' {
+			child = n.wildcard
+			tokens = tokens[:1]
+		}
+		if child == nil {
+			return n, false
 		}
+		newChild, ok := child.removeTokens(tokens[1:], pattern, false)
+		if !ok {
+			return n, false
+		}
+		if t.text[0] == ':' {
+			nn.param = newChild
+		} else {
+			nn.wildcard = newChild
+		}
+	} else {
+		i := strings.IndexByte(n.indices, t.text[0])
+		if i < 0 || !strings.HasPrefix(t.text, n.children[i].part) {
+			return n, false
+		}
+		child := n.children[i]
+		rest := tokens[1:]
+		if len(child.part) < len(t.text) {
+			// the static text continues in the children of child
+			rest = append([]token{{text: t.text[len(child.part):]}}, rest...)
+		}
+		newChild, ok := child.removeTokens(rest, pattern, false)
+		if !ok {
+			return n, false
+		}
+		nn.children = append([]*node(nil), n.children[:i]...)
+		nn.indices = n.indices[:i]
+		if newChild != nil {
+			nn.children = append(nn.children, newChild)
+			nn.indices += n.indices[i : i+1]
+		}
+		nn.children = append(nn.children, n.children[i+1:]...)
+		nn.indices += n.indices[i+1:]
+	}
+	if !isRoot && nn.isEmpty() {
+		return nil, true
 	}
+	return &nn, true
+}
 
+// search returns the route matching path, static children are preferred over
+// `:param`, which is preferred over `*wildcard`. path is the rest after n.part,
+// it must be cleaned by cleanPath. search doesn't allocate.
+func (n *node) search(path string) *node {
+	if path == "" {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.part) {
+			if result := child.search(path[len(child.part):]); result != nil {
+				return result
+			}
+		}
+	}
+	if n.param != nil {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			if result := n.param.search(path[end:]); result != nil {
+				return result
+			}
+		}
+	}
+	if n.wildcard != nil && n.wildcard.pattern != "" {
+		return n.wildcard
+	}
 	return nil
 }
 
@@ -59,23 +232,31 @@ func (n *node) travel(list *([]*node)) {
 	for _, child := range n.children {
 		child.travel(list)
 	}
+	if n.param != nil {
+		n.param.travel(list)
+	}
+	if n.wildcard != nil {
+		n.wildcard.travel(list)
+	}
 }
 
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
-		}
+// nextSegment returns the first segment of s and the rest after it
+func nextSegment(s string) (segment string, rest string) {
+	s = strings.TrimLeft(s, "/")
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		return s[:i], s[i:]
 	}
-	return nil
+	return s, ""
 }
 
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
-		}
+// cleanPath removes empty segments of path, like parsePattern does,
+// it only allocates if path contains `//`
+func cleanPath(path string) string {
+	for len(path) > 1 && path[len(path)-1] == '/' {
+		path = path[:len(path)-1]
+	}
+	if path == "" || path[0] != '/' || strings.Contains(path, "//") {
+		return "/" + strings.Join(parsePattern(path), "/")
 	}
-	return nodes
+	return path
 }
@@ -0,0 +1,231 @@
+package gee
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// marshalYAML encodes v as block style YAML, which is enough for responses.
+// Struct fields are named by the `yaml` tag or the lower-cased field name,
+// `yaml:"-"` skips a field and `omitempty` skips zero values.
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeYAML(&buf, reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// yamlFields returns the entries of a map or struct
+func yamlFields(v reflect.Value) ([]yamlField, error) {
+	var fields []yamlField
+	if v.Kind() == reflect.Map {
+		for _, key := range v.MapKeys() {
+			fields = append(fields, yamlField{key: fmt.Sprint(key.Interface()), value: v.MapIndex(key)})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].key < fields[j].key })
+		return fields, nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts := f.Name, ""
+		if tag, ok := f.Tag.Lookup("yaml"); ok {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.SplitN(tag, ",", 2)
+			if parts[0] != "" {
+				name = parts[0]
+			} else {
+				name = strings.ToLower(name)
+			}
+			if len(parts) == 2 {
+				opts = parts[1]
+			}
+		} else {
+			name = strings.ToLower(name)
+		}
+		value := v.Field(i)
+		if strings.Contains(opts, "omitempty") && isZero(value) {
+			continue
+		}
+		fields = append(fields, yamlField{key: name, value: value})
+	}
+	return fields, nil
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// yamlKind classifies v as a scalar, mapping or sequence
+func yamlKind(v reflect.Value) string {
+	if !v.IsValid() {
+		return "scalar"
+	}
+	if _, ok := v.Interface().(encoding.TextMarshaler); ok {
+		return "scalar"
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Struct:
+		return "mapping"
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return "scalar"
+		}
+		return "sequence"
+	case reflect.Array:
+		return "sequence"
+	}
+	return "scalar"
+}
+
+// encodeYAML writes v as a document or a nested block indented by indent spaces
+func encodeYAML(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	v = indirect(v)
+	pad := strings.Repeat(" ", indent)
+	switch yamlKind(v) {
+	case "mapping":
+		fields, err := yamlFields(v)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			buf.WriteString(pad + "{}\n")
+			return nil
+		}
+		for _, f := range fields {
+			buf.WriteString(pad + yamlScalarString(f.key) + ":")
+			if err := encodeYAMLValue(buf, f.value, indent+2); err != nil {
+				return err
+			}
+		}
+	case "sequence":
+		if v.Len() == 0 {
+			buf.WriteString(pad + "[]\n")
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			item := indirect(v.Index(i))
+			if yamlKind(item) == "scalar" {
+				s, err := yamlScalar(item)
+				if err != nil {
+					return err
+				}
+				buf.WriteString(pad + "- " + s + "\n")
+				continue
+			}
+			// write the nested block and replace its first indent by `- `
+			var nested bytes.Buffer
+			if err := encodeYAML(&nested, item, indent+2); err != nil {
+				return err
+			}
+			buf.WriteString(pad + "- ")
+			buf.Write(nested.Bytes()[indent+2:])
+		}
+	default:
+		s, err := yamlScalar(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(pad + s + "\n")
+	}
+	return nil
+}
+
+// encodeYAMLValue writes the value of a mapping entry after `key:`
+func encodeYAMLValue(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	v = indirect(v)
+	switch yamlKind(v) {
+	case "mapping", "sequence":
+		if v.Kind() != reflect.Struct && v.Len() == 0 {
+			if v.Kind() == reflect.Map {
+				buf.WriteString(" {}\n")
+			} else {
+				buf.WriteString(" []\n")
+			}
+			return nil
+		}
+		buf.WriteString("\n")
+		return encodeYAML(buf, v, indent)
+	}
+	s, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(" " + s + "\n")
+	return nil
+}
+
+func yamlScalar(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "null", nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return yamlScalarString(string(text)), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return yamlScalarString(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.Slice:
+		// []byte
+		return yamlScalarString(string(v.Bytes())), nil
+	}
+	return "", fmt.Errorf("gee: yaml: unsupported type %s", v.Type())
+}
+
+// yamlScalarString quotes s if it would be read as another type or break the syntax
+func yamlScalarString(s string) string {
+	if s == "" || s != strings.TrimSpace(s) || strings.ContainsAny(s, ":#{}[],&*!|>'\"%@`\n\t\\") ||
+		strings.ContainsAny(s[:1], "-?") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}